@@ -0,0 +1,70 @@
+package asr
+
+// Middleware wraps a provider to add behavior around its Fetch and Parse
+// calls, such as logging, caching of raw results or metrics.
+//
+// Example:
+//
+//	type timedProvider struct {
+//	    asr.Provider
+//	}
+//
+//	func (p *timedProvider) Fetch(ctx context.Context, audioPath string, opts asr.FetchOptions) (asr.RawResult, error) {
+//	    start := time.Now()
+//	    defer func() { log.Printf("%s: fetched in %s", p.Name(), time.Since(start)) }()
+//	    return p.Provider.Fetch(ctx, audioPath, opts)
+//	}
+//
+//	asr.Use(func(next asr.Provider) asr.Provider {
+//	    return &timedProvider{Provider: next}
+//	})
+type Middleware func(next Provider) Provider
+
+// Chain wraps provider with middlewares. The first middleware is the
+// outermost: Chain(p, a, b) returns a(b(p)).
+func Chain(provider Provider, middlewares ...Middleware) Provider {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		provider = middlewares[i](provider)
+	}
+	return provider
+}
+
+// Use installs middlewares for every provider of the global registry.
+//
+// See Registry.Use. This function is safe for concurrent use.
+func Use(middlewares ...Middleware) {
+	globalRegistry.Use(middlewares...)
+}
+
+// UseFor installs middlewares for one provider of the global registry.
+//
+// See Registry.UseFor. This function is safe for concurrent use.
+func UseFor(name string, middlewares ...Middleware) {
+	globalRegistry.UseFor(name, middlewares...)
+}
+
+// Use installs middlewares for every provider of this registry.
+//
+// Middlewares apply to the providers returned by Get, and so to
+// Transcribe, including providers registered later. They are added after
+// the middlewares installed before, and wrap the middlewares installed
+// with UseFor. This method is safe for concurrent use.
+func (r *Registry) Use(middlewares ...Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+// UseFor installs middlewares for the provider named name in this
+// registry, which need not be registered yet.
+//
+// They are added after the middlewares installed before for the same
+// provider. This method is safe for concurrent use.
+func (r *Registry) UseFor(name string, middlewares ...Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.entryMiddlewares == nil {
+		r.entryMiddlewares = make(map[string][]Middleware)
+	}
+	r.entryMiddlewares[name] = append(r.entryMiddlewares[name], middlewares...)
+}
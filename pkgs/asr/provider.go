@@ -22,7 +22,31 @@
 //	}
 package asr
 
-import "context"
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// DefaultHTTPClient is the HTTP client used by providers whose options do
+// not set one.
+//
+// Uploading and transcribing long audio can take a while, hence the long
+// timeout. Replace it to use a proxy, custom CAs or a custom transport for
+// every provider.
+var DefaultHTTPClient = &http.Client{Timeout: 2 * time.Hour}
+
+// ClientOr returns client, or DefaultHTTPClient if client is nil.
+//
+// Providers use it to pick the HTTP client of their requests:
+//
+//	resp, err := asr.ClientOr(opts.HTTPClient).Do(req)
+func ClientOr(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return DefaultHTTPClient
+}
 
 // Provider defines the interface that all ASR providers must implement.
 //
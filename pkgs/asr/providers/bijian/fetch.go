@@ -10,14 +10,16 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"github.com/xifan2333/2sub/pkgs/asr"
 )
 
 const (
-	apiBaseURL      = "https://member.bilibili.com/x/bcut/rubick-interface"
-	apiReqUpload    = apiBaseURL + "/resource/create"
-	apiCommitUpload = apiBaseURL + "/resource/create/complete"
-	apiCreateTask   = apiBaseURL + "/task"
-	apiQueryResult  = apiBaseURL + "/task/result"
+	defaultBaseURL  = "https://member.bilibili.com/x/bcut/rubick-interface"
+	apiReqUpload    = "/resource/create"
+	apiCommitUpload = "/resource/create/complete"
+	apiCreateTask   = "/task"
+	apiQueryResult  = "/task/result"
 )
 
 // fetch executes the complete Bijian ASR transcription workflow
@@ -71,7 +73,7 @@ func requestUpload(ctx context.Context, audioData []byte, opts *Options) (map[st
 		"model_id":         "8",
 	}
 
-	resp, err := doRequest(ctx, "POST", apiReqUpload, payload, opts)
+	resp, err := doRequest(ctx, "POST", apiURL(opts, apiReqUpload), payload, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -133,8 +135,7 @@ func uploadPart(ctx context.Context, url string, data []byte, opts *Options) (st
 	}
 	req = req.WithContext(ctx)
 
-	client := &http.Client{Timeout: 2 * time.Hour}
-	resp, err := client.Do(req)
+	resp, err := asr.ClientOr(opts.HTTPClient).Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -159,7 +160,7 @@ func commitUpload(ctx context.Context, uploadResp map[string]interface{}, etags
 		"model_id":   "8",
 	}
 
-	resp, err := doRequest(ctx, "POST", apiCommitUpload, payload, opts)
+	resp, err := doRequest(ctx, "POST", apiURL(opts, apiCommitUpload), payload, opts)
 	if err != nil {
 		return "", err
 	}
@@ -184,7 +185,7 @@ func createTask(ctx context.Context, downloadURL string, opts *Options) (string,
 		"model_id": "8",
 	}
 
-	resp, err := doRequest(ctx, "POST", apiCreateTask, payload, opts)
+	resp, err := doRequest(ctx, "POST", apiURL(opts, apiCreateTask), payload, opts)
 	if err != nil {
 		return "", err
 	}
@@ -251,7 +252,7 @@ func pollResult(ctx context.Context, taskID string, opts *Options) (map[string]i
 
 // queryResult queries task result
 func queryResult(ctx context.Context, taskID string, opts *Options) (map[string]interface{}, error) {
-	url := fmt.Sprintf("%s?model_id=7&task_id=%s", apiQueryResult, taskID)
+	url := fmt.Sprintf("%s?model_id=7&task_id=%s", apiURL(opts, apiQueryResult), taskID)
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -264,8 +265,7 @@ func queryResult(ctx context.Context, taskID string, opts *Options) (map[string]
 	}
 	req = req.WithContext(ctx)
 
-	client := &http.Client{Timeout: 2 * time.Hour}
-	resp, err := client.Do(req)
+	resp, err := asr.ClientOr(opts.HTTPClient).Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -284,6 +284,11 @@ func queryResult(ctx context.Context, taskID string, opts *Options) (map[string]
 	return result, nil
 }
 
+// apiURL returns the URL of an API path
+func apiURL(opts *Options, path string) string {
+	return strings.TrimSuffix(opts.BaseURL, "/") + path
+}
+
 // doRequest executes an HTTP JSON request
 func doRequest(ctx context.Context, method, url string, payload map[string]interface{}, opts *Options) (map[string]interface{}, error) {
 	jsonData, err := json.Marshal(payload)
@@ -303,8 +308,7 @@ func doRequest(ctx context.Context, method, url string, payload map[string]inter
 	}
 	req = req.WithContext(ctx)
 
-	client := &http.Client{Timeout: 2 * time.Hour}
-	resp, err := client.Do(req)
+	resp, err := asr.ClientOr(opts.HTTPClient).Do(req)
 	if err != nil {
 		return nil, err
 	}
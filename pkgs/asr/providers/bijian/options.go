@@ -1,19 +1,34 @@
 package bijian
 
+import "net/http"
+
 // Options contains Bijian-specific fetch options.
 type Options struct {
 	// Cookie is the optional authentication cookie.
 	// If not provided, the request may work without authentication
 	// depending on the API's current access policy.
 	Cookie string
+
+	// BaseURL is the base URL of the Bijian API.
+	// Default: "https://member.bilibili.com/x/bcut/rubick-interface"
+	BaseURL string
+
+	// HTTPClient is the HTTP client used for the requests.
+	// Default: asr.DefaultHTTPClient
+	HTTPClient *http.Client
 }
 
 // Validate validates the options and sets default values.
 //
+// Default values:
+//   - BaseURL: the public endpoint if not specified
+//
 // This method always returns nil as Cookie is optional and there are
 // no other validation requirements.
 func (o *Options) Validate() error {
-	// No specific validation needed for Bijian options
 	// Cookie is optional
+	if o.BaseURL == "" {
+		o.BaseURL = defaultBaseURL
+	}
 	return nil
 }
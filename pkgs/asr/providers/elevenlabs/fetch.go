@@ -11,14 +11,17 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
-	"time"
+	"strings"
 
 	"github.com/brianvoe/gofakeit/v6"
+
+	"github.com/xifan2333/2sub/pkgs/asr"
 )
 
 const (
-	apiURL  = "https://api.elevenlabs.io/v1/speech-to-text"
-	modelID = "scribe_v1"
+	defaultBaseURL = "https://api.elevenlabs.io"
+	apiPath        = "/v1/speech-to-text"
+	modelID        = "scribe_v1"
 )
 
 // fetch executes the ElevenLabs ASR transcription
@@ -80,7 +83,7 @@ func fetch(ctx context.Context, audioPath string, opts *Options) (map[string]int
 	}
 
 	// Create request
-	req, err := http.NewRequest("POST", apiURL, &requestBody)
+	req, err := http.NewRequest("POST", strings.TrimSuffix(opts.BaseURL, "/")+apiPath, &requestBody)
 	if err != nil {
 		return nil, &FetchError{Step: "create_request", Message: "failed to create HTTP request", Err: err}
 	}
@@ -107,8 +110,7 @@ func fetch(ctx context.Context, audioPath string, opts *Options) (map[string]int
 	req = req.WithContext(ctx)
 
 	// Send request
-	client := &http.Client{Timeout: 2 * time.Hour}
-	resp, err := client.Do(req)
+	resp, err := asr.ClientOr(opts.HTTPClient).Do(req)
 	if err != nil {
 		return nil, &FetchError{Step: "http_request", Message: "HTTP request failed", Err: err}
 	}
@@ -1,5 +1,7 @@
 package elevenlabs
 
+import "net/http"
+
 // Options contains ElevenLabs-specific fetch options.
 type Options struct {
 	// LanguageCode specifies the language code for transcription.
@@ -11,12 +13,21 @@ type Options struct {
 	// When enabled, the API will identify and tag non-speech audio events.
 	// Default: false
 	TagAudioEvents bool
+
+	// BaseURL is the base URL of the ElevenLabs API.
+	// Default: "https://api.elevenlabs.io"
+	BaseURL string
+
+	// HTTPClient is the HTTP client used for the requests.
+	// Default: asr.DefaultHTTPClient
+	HTTPClient *http.Client
 }
 
 // Validate validates the options and sets default values.
 //
 // Default values:
 //   - LanguageCode: "auto" if not specified
+//   - BaseURL: the public endpoint if not specified
 //
 // This method always returns nil as all option combinations are valid.
 func (o *Options) Validate() error {
@@ -24,6 +35,9 @@ func (o *Options) Validate() error {
 	if o.LanguageCode == "" {
 		o.LanguageCode = "auto"
 	}
+	if o.BaseURL == "" {
+		o.BaseURL = defaultBaseURL
+	}
 
 	return nil
 }
@@ -17,15 +17,17 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/xifan2333/2sub/pkgs/asr"
 )
 
 const (
-	apiBaseURL    = "https://lv-pc-api-sinfonlinec.ulikecam.com"
-	apiUploadSign = apiBaseURL + "/lv/v1/upload_sign"
-	apiSubmit     = apiBaseURL + "/lv/v1/audio_subtitle/submit"
-	apiQuery      = apiBaseURL + "/lv/v1/audio_subtitle/query"
-	vodBaseURL    = "https://vod.bytedanceapi.com"
-	uploadUA      = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/81.0.4044.138 Safari/537.36 Thea/1.0.1"
+	defaultBaseURL    = "https://lv-pc-api-sinfonlinec.ulikecam.com"
+	apiUploadSign     = "/lv/v1/upload_sign"
+	apiSubmit         = "/lv/v1/audio_subtitle/submit"
+	apiQuery          = "/lv/v1/audio_subtitle/query"
+	defaultVODBaseURL = "https://vod.bytedanceapi.com"
+	uploadUA          = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/81.0.4044.138 Safari/537.36 Thea/1.0.1"
 )
 
 // uploadContext holds upload-related context information
@@ -61,27 +63,27 @@ func fetch(ctx context.Context, audioPath string, opts *Options) (map[string]int
 	}
 
 	// Step 1: Get upload signature (AWS credentials)
-	if err := getUploadSign(ctx, uploadCtx, tdid); err != nil {
+	if err := getUploadSign(ctx, uploadCtx, tdid, opts); err != nil {
 		return nil, &FetchError{Step: "upload_sign", Message: "failed to get upload signature", Err: err}
 	}
 
 	// Step 2: Get upload authorization
-	if err := getUploadAuth(ctx, uploadCtx, len(audioData)); err != nil {
+	if err := getUploadAuth(ctx, uploadCtx, len(audioData), opts); err != nil {
 		return nil, &FetchError{Step: "upload_auth", Message: "failed to get upload authorization", Err: err}
 	}
 
 	// Step 3: Upload file
-	if err := uploadFile(ctx, uploadCtx, audioData); err != nil {
+	if err := uploadFile(ctx, uploadCtx, audioData, opts); err != nil {
 		return nil, &FetchError{Step: "upload_file", Message: "failed to upload file", Err: err}
 	}
 
 	// Step 4: Check upload
-	if err := uploadCheck(ctx, uploadCtx); err != nil {
+	if err := uploadCheck(ctx, uploadCtx, opts); err != nil {
 		return nil, &FetchError{Step: "upload_check", Message: "failed to check upload", Err: err}
 	}
 
 	// Step 5: Commit upload
-	if err := uploadCommit(ctx, uploadCtx, audioData); err != nil {
+	if err := uploadCommit(ctx, uploadCtx, audioData, opts); err != nil {
 		return nil, &FetchError{Step: "upload_commit", Message: "failed to commit upload", Err: err}
 	}
 
@@ -92,7 +94,7 @@ func fetch(ctx context.Context, audioPath string, opts *Options) (map[string]int
 	}
 
 	// Step 7: Query result
-	result, err := queryTask(ctx, queryID, tdid)
+	result, err := queryTask(ctx, queryID, tdid, opts)
 	if err != nil {
 		return nil, &FetchError{Step: "query_result", Message: "failed to query result", Err: err}
 	}
@@ -147,7 +149,7 @@ func getTDIDMAC() string {
 }
 
 // getUploadSign gets the upload signature
-func getUploadSign(ctx context.Context, uploadCtx *uploadContext, tdid string) error {
+func getUploadSign(ctx context.Context, uploadCtx *uploadContext, tdid string, opts *Options) error {
 	payload := map[string]interface{}{
 		"biz": "pc-recognition",
 	}
@@ -158,7 +160,7 @@ func getUploadSign(ctx context.Context, uploadCtx *uploadContext, tdid string) e
 	}
 
 	headers := buildHeaders(sign, deviceTime, tdid)
-	resp, err := doRequest(ctx, "POST", apiUploadSign, payload, headers)
+	resp, err := doRequest(ctx, "POST", apiURL(opts.BaseURL, apiUploadSign), payload, headers, opts)
 	if err != nil {
 		return err
 	}
@@ -176,7 +178,7 @@ func getUploadSign(ctx context.Context, uploadCtx *uploadContext, tdid string) e
 }
 
 // getUploadAuth gets upload authorization
-func getUploadAuth(ctx context.Context, uploadCtx *uploadContext, fileSize int) error {
+func getUploadAuth(ctx context.Context, uploadCtx *uploadContext, fileSize int, opts *Options) error {
 	requestParams := fmt.Sprintf("Action=ApplyUploadInner&FileSize=%d&FileType=object&IsInner=1&SpaceName=lv-mac-recognition&Version=2020-11-19&s=5y0udbjapi", fileSize)
 
 	t := time.Now().UTC()
@@ -192,7 +194,7 @@ func getUploadAuth(ctx context.Context, uploadCtx *uploadContext, fileSize int)
 	authHeader := fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s/cn/vod/aws4_request, SignedHeaders=x-amz-date;x-amz-security-token, Signature=%s",
 		uploadCtx.accessKey, datestamp, signature)
 
-	req, err := http.NewRequest("GET", vodBaseURL+"/?"+requestParams, nil)
+	req, err := http.NewRequest("GET", apiURL(opts.VODBaseURL, "/?"+requestParams), nil)
 	if err != nil {
 		return err
 	}
@@ -203,8 +205,7 @@ func getUploadAuth(ctx context.Context, uploadCtx *uploadContext, fileSize int)
 	req.Header.Set("authorization", authHeader)
 	req = req.WithContext(ctx)
 
-	client := &http.Client{Timeout: 2 * time.Hour}
-	resp, err := client.Do(req)
+	resp, err := asr.ClientOr(opts.HTTPClient).Do(req)
 	if err != nil {
 		return err
 	}
@@ -260,7 +261,7 @@ func getUploadAuth(ctx context.Context, uploadCtx *uploadContext, fileSize int)
 }
 
 // uploadFile uploads the audio file
-func uploadFile(ctx context.Context, uploadCtx *uploadContext, audioData []byte) error {
+func uploadFile(ctx context.Context, uploadCtx *uploadContext, audioData []byte, opts *Options) error {
 	reqURL := fmt.Sprintf("https://%s/%s", uploadCtx.uploadHost, uploadCtx.storeURI)
 
 	req, err := http.NewRequest("PUT", reqURL, bytes.NewReader(audioData))
@@ -279,8 +280,7 @@ func uploadFile(ctx context.Context, uploadCtx *uploadContext, audioData []byte)
 	req.Header.Set("Content-Type", "application/octet-stream")
 	req = req.WithContext(ctx)
 
-	client := &http.Client{Timeout: 2 * time.Hour}
-	resp, err := client.Do(req)
+	resp, err := asr.ClientOr(opts.HTTPClient).Do(req)
 	if err != nil {
 		return err
 	}
@@ -312,7 +312,7 @@ func uploadFile(ctx context.Context, uploadCtx *uploadContext, audioData []byte)
 }
 
 // uploadCheck checks the upload
-func uploadCheck(ctx context.Context, uploadCtx *uploadContext) error {
+func uploadCheck(ctx context.Context, uploadCtx *uploadContext, opts *Options) error {
 	reqURL := fmt.Sprintf("https://%s/%s", uploadCtx.uploadHost, uploadCtx.storeURI)
 	payload := fmt.Sprintf("1:%s", uploadCtx.crc32Hex)
 
@@ -330,8 +330,7 @@ func uploadCheck(ctx context.Context, uploadCtx *uploadContext) error {
 	req.Header.Set("Content-CRC32", uploadCtx.crc32Hex)
 	req = req.WithContext(ctx)
 
-	client := &http.Client{Timeout: 2 * time.Hour}
-	resp, err := client.Do(req)
+	resp, err := asr.ClientOr(opts.HTTPClient).Do(req)
 	if err != nil {
 		return err
 	}
@@ -361,7 +360,7 @@ func uploadCheck(ctx context.Context, uploadCtx *uploadContext) error {
 }
 
 // uploadCommit commits the upload
-func uploadCommit(ctx context.Context, uploadCtx *uploadContext, audioData []byte) error {
+func uploadCommit(ctx context.Context, uploadCtx *uploadContext, audioData []byte, opts *Options) error {
 	reqURL := fmt.Sprintf("https://%s/%s", uploadCtx.uploadHost, uploadCtx.storeURI)
 
 	req, err := http.NewRequest("PUT", reqURL, bytes.NewReader(audioData))
@@ -380,8 +379,7 @@ func uploadCommit(ctx context.Context, uploadCtx *uploadContext, audioData []byt
 	req.Header.Set("Content-CRC32", uploadCtx.crc32Hex)
 	req = req.WithContext(ctx)
 
-	client := &http.Client{Timeout: 2 * time.Hour}
-	resp, err := client.Do(req)
+	resp, err := asr.ClientOr(opts.HTTPClient).Do(req)
 	if err != nil {
 		return err
 	}
@@ -434,7 +432,7 @@ func submitTask(ctx context.Context, uploadCtx *uploadContext, opts *Options, td
 	}
 
 	headers := buildHeaders(sign, deviceTime, tdid)
-	resp, err := doRequest(ctx, "POST", apiSubmit, payload, headers)
+	resp, err := doRequest(ctx, "POST", apiURL(opts.BaseURL, apiSubmit), payload, headers, opts)
 	if err != nil {
 		return "", err
 	}
@@ -449,7 +447,7 @@ func submitTask(ctx context.Context, uploadCtx *uploadContext, opts *Options, td
 }
 
 // queryTask queries task result
-func queryTask(ctx context.Context, queryID string, tdid string) (map[string]interface{}, error) {
+func queryTask(ctx context.Context, queryID string, tdid string, opts *Options) (map[string]interface{}, error) {
 	payload := map[string]interface{}{
 		"id": queryID,
 		"pack_options": map[string]interface{}{
@@ -463,7 +461,7 @@ func queryTask(ctx context.Context, queryID string, tdid string) (map[string]int
 	}
 
 	headers := buildHeaders(sign, deviceTime, tdid)
-	resp, err := doRequest(ctx, "POST", apiQuery, payload, headers)
+	resp, err := doRequest(ctx, "POST", apiURL(opts.BaseURL, apiQuery), payload, headers, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -508,8 +506,13 @@ func buildHeaders(sign, deviceTime, tdid string) map[string]string {
 	}
 }
 
+// apiURL returns the URL of an API path
+func apiURL(baseURL, path string) string {
+	return strings.TrimSuffix(baseURL, "/") + path
+}
+
 // doRequest executes an HTTP JSON request
-func doRequest(ctx context.Context, method, url string, payload map[string]interface{}, headers map[string]string) (map[string]interface{}, error) {
+func doRequest(ctx context.Context, method, url string, payload map[string]interface{}, headers map[string]string, opts *Options) (map[string]interface{}, error) {
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return nil, err
@@ -526,8 +529,7 @@ func doRequest(ctx context.Context, method, url string, payload map[string]inter
 	}
 	req = req.WithContext(ctx)
 
-	client := &http.Client{Timeout: 2 * time.Hour}
-	resp, err := client.Do(req)
+	resp, err := asr.ClientOr(opts.HTTPClient).Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -1,5 +1,7 @@
 package jianying
 
+import "net/http"
+
 // Options contains JianYing-specific fetch options.
 type Options struct {
 	// StartTime is the audio start time in seconds (default: 0).
@@ -10,12 +12,26 @@ type Options struct {
 	// This allows transcribing only a portion of the audio file.
 	// The default value of 6000 seconds (100 minutes) is sufficient for most use cases.
 	EndTime float64
+
+	// BaseURL is the base URL of the JianYing API.
+	// Default: "https://lv-pc-api-sinfonlinec.ulikecam.com"
+	BaseURL string
+
+	// VODBaseURL is the base URL of the ByteDance VOD API used to
+	// authorize uploads.
+	// Default: "https://vod.bytedanceapi.com"
+	VODBaseURL string
+
+	// HTTPClient is the HTTP client used for the requests.
+	// Default: asr.DefaultHTTPClient
+	HTTPClient *http.Client
 }
 
 // Validate validates the options and sets default values.
 //
 // Default values:
 //   - EndTime: 6000 seconds if not specified or zero
+//   - BaseURL, VODBaseURL: the public endpoints if not specified
 //
 // Returns an error if:
 //   - StartTime is negative
@@ -25,6 +41,12 @@ func (o *Options) Validate() error {
 	if o.EndTime == 0 {
 		o.EndTime = 6000 // Set default end time
 	}
+	if o.BaseURL == "" {
+		o.BaseURL = defaultBaseURL
+	}
+	if o.VODBaseURL == "" {
+		o.VODBaseURL = defaultVODBaseURL
+	}
 
 	if o.StartTime < 0 {
 		return &ValidationError{Field: "StartTime", Message: "must be non-negative"}
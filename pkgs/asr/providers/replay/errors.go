@@ -0,0 +1,48 @@
+package replay
+
+import "fmt"
+
+// ValidationError represents a validation error
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("validation error on field '%s': %s", e.Field, e.Message)
+}
+
+// FetchError represents an error while loading a fixture
+type FetchError struct {
+	Step    string
+	Message string
+	Err     error
+}
+
+func (e *FetchError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("fetch error at step '%s': %s: %v", e.Step, e.Message, e.Err)
+	}
+	return fmt.Sprintf("fetch error at step '%s': %s", e.Step, e.Message)
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError represents an error during parse operation
+type ParseError struct {
+	Message string
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("parse error: %s: %v", e.Message, e.Err)
+	}
+	return fmt.Sprintf("parse error: %s", e.Message)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
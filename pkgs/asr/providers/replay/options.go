@@ -0,0 +1,26 @@
+package replay
+
+// Options contains replay-specific fetch options.
+type Options struct {
+	// Provider is the name of the provider whose Parse the fixture is
+	// replayed through, e.g. "jianying".
+	// Default: the name of the directory of the fixture, as laid out by
+	// Record
+	Provider string
+
+	// Fixture is the path of the recorded raw response.
+	// Default: the audio path passed to Fetch
+	Fixture string
+}
+
+// Validate validates the options and sets default values.
+//
+// Returns an error if:
+//   - Provider is "replay"
+func (o *Options) Validate() error {
+	if o.Provider == "replay" {
+		return &ValidationError{Field: "Provider", Message: "cannot replay through the replay provider"}
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+// Package replay provides an ASR provider that replays recorded raw
+// responses through the Parse of another provider, without network access.
+//
+// Features:
+//   - Fixtures are the raw responses of the real APIs, stored as JSON
+//   - Recording of fixtures from live transcriptions with Record
+//
+// Fixtures are laid out by provider, e.g. testdata/jianying/interview.json
+// for a JianYing response. The provider being replayed must be registered.
+//
+// Example usage:
+//
+//	import (
+//	    "context"
+//	    "github.com/xifan2333/2sub/asr"
+//	    _ "github.com/xifan2333/2sub/asr/providers/jianying"
+//	    "github.com/xifan2333/2sub/asr/providers/replay"
+//	)
+//
+//	// Record the responses of live transcriptions once
+//	asr.Use(replay.Record("testdata"))
+//	asr.Transcribe(ctx, "jianying", "interview.mp3", nil)
+//
+//	// Replay them offline
+//	result, err := asr.Transcribe(ctx, "replay", "testdata/jianying/interview.json", nil)
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xifan2333/2sub/pkgs/asr"
+)
+
+// Provider implements the ASR provider interface by replaying fixtures.
+type Provider struct{}
+
+// Ensure Provider implements asr.Provider interface at compile time.
+var _ asr.Provider = (*Provider)(nil)
+
+func init() {
+	// Register the provider on package initialization.
+	// This allows the provider to be used via asr.Get("replay")
+	// or asr.Transcribe(ctx, "replay", ...).
+	asr.Register(&Provider{})
+}
+
+// Recording is the raw result of the replay provider: a recorded raw
+// response and the provider that returned it.
+type Recording struct {
+	// Provider is the name of the provider that returned the response.
+	Provider string
+
+	// Response is the raw response.
+	Response map[string]interface{}
+}
+
+// Name returns the provider's unique identifier.
+//
+// Returns "replay".
+func (p *Provider) Name() string {
+	return "replay"
+}
+
+// Fetch loads a recorded raw response.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - audioPath: Path to the fixture, unless opts sets Fixture
+//   - opts: Replay-specific options (nil will use defaults)
+//
+// Returns a *Recording.
+func (p *Provider) Fetch(ctx context.Context, audioPath string, opts asr.FetchOptions) (asr.RawResult, error) {
+	// Validate and convert options
+	replayOpts, ok := opts.(*Options)
+	if !ok || replayOpts == nil {
+		replayOpts = &Options{} // Use default options
+	}
+
+	if err := replayOpts.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	fixture := replayOpts.Fixture
+	if fixture == "" {
+		fixture = audioPath
+	}
+	provider := replayOpts.Provider
+	if provider == "" {
+		provider = filepath.Base(filepath.Dir(fixture))
+	}
+
+	data, err := os.ReadFile(fixture)
+	if err != nil {
+		return nil, &FetchError{Step: "read_file", Message: "failed to read fixture", Err: err}
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, &FetchError{Step: "decode", Message: "failed to decode fixture", Err: err}
+	}
+
+	return &Recording{Provider: provider, Response: response}, nil
+}
+
+// Parse converts the recorded response to standardized format with the
+// Parse of the provider that returned it.
+//
+// Returns an error if the provider is not registered, or the error of
+// its Parse.
+func (p *Provider) Parse(raw asr.RawResult) (*asr.StandardResult, error) {
+	recording, ok := raw.(*Recording)
+	if !ok || recording == nil {
+		return nil, &ParseError{Message: "invalid raw result type, expected *replay.Recording"}
+	}
+
+	if recording.Provider == p.Name() {
+		return nil, &ParseError{Message: "cannot replay through the replay provider"}
+	}
+
+	provider, err := asr.Get(recording.Provider)
+	if err != nil {
+		return nil, &ParseError{Message: "provider of the recording is not registered", Err: err}
+	}
+
+	return provider.Parse(recording.Response)
+}
+
+// Record returns a middleware that saves the raw responses fetched by the
+// wrapped provider as fixtures in dir.
+//
+// The response for audio.mp3 fetched by jianying is saved as
+// dir/jianying/audio.json. Failing to save a fixture does not fail the
+// fetch.
+func Record(dir string) asr.Middleware {
+	return func(next asr.Provider) asr.Provider {
+		return &recorder{Provider: next, dir: dir}
+	}
+}
+
+// recorder saves the raw responses of the wrapped provider.
+type recorder struct {
+	asr.Provider
+	dir string
+}
+
+// Fetch performs ASR transcription and saves the raw response.
+func (r *recorder) Fetch(ctx context.Context, audioPath string, opts asr.FetchOptions) (asr.RawResult, error) {
+	raw, err := r.Provider.Fetch(ctx, audioPath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replayed responses are fixtures already
+	if _, ok := raw.(*Recording); ok {
+		return raw, nil
+	}
+
+	data, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return raw, nil
+	}
+
+	name := strings.TrimSuffix(filepath.Base(audioPath), filepath.Ext(audioPath)) + ".json"
+	dir := filepath.Join(r.dir, r.Name())
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return raw, nil
+	}
+	os.WriteFile(filepath.Join(dir, name), append(data, '\n'), 0o644)
+
+	return raw, nil
+}
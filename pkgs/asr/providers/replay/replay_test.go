@@ -0,0 +1,133 @@
+package replay_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/xifan2333/2sub/pkgs/asr/providers/bijian"
+	_ "github.com/xifan2333/2sub/pkgs/asr/providers/elevenlabs"
+	_ "github.com/xifan2333/2sub/pkgs/asr/providers/jianying"
+	"github.com/xifan2333/2sub/pkgs/asr/providers/replay"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestParseGolden(t *testing.T) {
+	golden(t, "testdata", *update)
+}
+
+// goldenSuffix is the suffix of the golden file of a fixture.
+const goldenSuffix = ".golden.json"
+
+// golden replays every fixture in dir and compares the parsed result with
+// its golden file, in a subtest per fixture.
+//
+// Fixtures are laid out as by Record: dir/<provider>/<name>.json, with the
+// expected StandardResult JSON in dir/<provider>/<name>.golden.json. When
+// Parse fails, the golden file holds {"error": "<message>"} instead. If
+// update is set, golden files are written instead of compared.
+func golden(t *testing.T, dir string, update bool) {
+	t.Helper()
+
+	fixtures, err := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+	if err != nil {
+		t.Fatalf("failed to list fixtures: %v", err)
+	}
+
+	found := false
+	for _, fixture := range fixtures {
+		if strings.HasSuffix(fixture, goldenSuffix) {
+			continue
+		}
+		found = true
+
+		name, _ := filepath.Rel(dir, fixture)
+		t.Run(filepath.ToSlash(name), func(t *testing.T) {
+			checkGolden(t, fixture, update)
+		})
+	}
+
+	if !found {
+		t.Fatalf("no fixtures found in %s", dir)
+	}
+}
+
+// checkGolden replays a fixture and compares the result with its golden
+// file.
+func checkGolden(t *testing.T, fixture string, update bool) {
+	t.Helper()
+
+	got, err := replayJSON(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	goldenPath := strings.TrimSuffix(fixture, ".json") + goldenSuffix
+	if update {
+		if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+			t.Fatalf("failed to write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("result differs from %s (run with -update to accept it):\n%s", goldenPath, diffLine(want, got))
+	}
+}
+
+// replayJSON replays a fixture and returns the indented JSON of the result,
+// or of the parse error.
+func replayJSON(fixture string) ([]byte, error) {
+	provider := &replay.Provider{}
+
+	raw, err := provider.Fetch(context.Background(), fixture, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	result, err := provider.Parse(raw)
+	if err != nil {
+		value = map[string]string{"error": err.Error()}
+	} else {
+		value = result
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// diffLine describes the first line that differs between want and got.
+func diffLine(want, got []byte) string {
+	wantLines := strings.Split(string(want), "\n")
+	gotLines := strings.Split(string(got), "\n")
+
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return fmt.Sprintf("line %d:\n  want: %s\n  got:  %s", i+1, w, g)
+		}
+	}
+	return ""
+}
@@ -11,8 +11,10 @@ import (
 // The registry is thread-safe and can be accessed concurrently.
 // Providers are typically registered during package initialization using init() functions.
 type Registry struct {
-	mu        sync.RWMutex
-	providers map[string]Provider
+	mu               sync.RWMutex
+	providers        map[string]Provider
+	middlewares      []Middleware
+	entryMiddlewares map[string][]Middleware
 }
 
 // globalRegistry is the default registry used by package-level functions.
@@ -72,19 +74,23 @@ func (r *Registry) Register(provider Provider) {
 	r.providers[provider.Name()] = provider
 }
 
-// Get retrieves a provider by name from this registry.
+// Get retrieves a provider by name from this registry, wrapped with the
+// middlewares installed with Use and UseFor.
 //
 // Returns an error if the provider is not found.
 // This method is safe for concurrent use.
 func (r *Registry) Get(name string) (Provider, error) {
 	r.mu.RLock()
-	defer r.mu.RUnlock()
-
 	provider, ok := r.providers[name]
+	middlewares := make([]Middleware, 0, len(r.middlewares)+len(r.entryMiddlewares[name]))
+	middlewares = append(middlewares, r.middlewares...)
+	middlewares = append(middlewares, r.entryMiddlewares[name]...)
+	r.mu.RUnlock()
+
 	if !ok {
 		return nil, fmt.Errorf("provider '%s' not found", name)
 	}
-	return provider, nil
+	return Chain(provider, middlewares...), nil
 }
 
 // List returns all registered provider names from this registry.
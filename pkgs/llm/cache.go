@@ -0,0 +1,387 @@
+package llm
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CacheStore stores cached responses by key.
+//
+// Implementations must be safe for concurrent use. Expiry is handled by
+// the cache, so stores only need to keep the values.
+type CacheStore interface {
+	// Get returns the value stored for key, or false if there is none.
+	Get(key string) ([]byte, bool, error)
+
+	// Set stores value for key, replacing any previous value.
+	Set(key string, value []byte) error
+
+	// Delete removes the value stored for key, if any.
+	Delete(key string) error
+}
+
+// cacheBypassKey is the context key of WithCacheBypass.
+type cacheBypassKey struct{}
+
+// WithCacheBypass returns a context that makes cached providers skip the
+// lookup and call the backend. The fresh result still replaces the cached
+// one.
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// cacheBypassed reports whether ctx was created by WithCacheBypass.
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// CacheKey returns the cache key of a request to provider: a hash of the
+// provider name and every option that affects the response.
+//
+// The API key is not part of the key. Messages with the same content
+// written as Content or as a single text part hash the same, and a zero
+// Temperature hashes as the default 1.0.
+func CacheKey(provider string, opts *Options) string {
+	temperature := opts.Temperature
+	if temperature == 0 {
+		temperature = 1.0
+	}
+
+	type keyMessage struct {
+		Role       string     `json:"role"`
+		Parts      []Part     `json:"parts"`
+		Name       string     `json:"name,omitempty"`
+		ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+		ToolCallID string     `json:"tool_call_id,omitempty"`
+	}
+	messages := make([]keyMessage, 0, len(opts.Messages))
+	for _, msg := range opts.Messages {
+		messages = append(messages, keyMessage{
+			Role:       msg.Role,
+			Parts:      msg.ContentParts(),
+			Name:       msg.Name,
+			ToolCalls:  msg.ToolCalls,
+			ToolCallID: msg.ToolCallID,
+		})
+	}
+
+	key := map[string]interface{}{
+		"provider":      provider,
+		"base_url":      opts.BaseURL,
+		"model":         opts.Model,
+		"messages":      messages,
+		"system_prompt": opts.SystemPrompt,
+		"temperature":   temperature,
+		"max_tokens":    opts.MaxTokens,
+		"top_p":         opts.TopP,
+		"stop":          opts.Stop,
+		"schema":        opts.Schema,
+		"tools":         opts.Tools,
+		"tool_choice":   opts.ToolChoice,
+		"extra":         opts.Extra,
+	}
+
+	// Maps are encoded with sorted keys, so the encoding is stable
+	data, err := json.Marshal(key)
+	if err != nil {
+		data = fmt.Appendf(nil, "%#v", key)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// cacheEntry is the stored form of a cached result.
+type cacheEntry struct {
+	ExpiresAt time.Time       `json:"expires_at,omitzero"`
+	Result    *StandardResult `json:"result"`
+}
+
+// WithCache wraps a provider so that its results are cached in store,
+// keyed by CacheKey.
+//
+// Cached results expire after ttl, or never if ttl is 0. Results served
+// from the cache have Cached set. Errors are never cached, nor are results
+// that do not conform to Options.Schema, and a failing store does not fail
+// the call.
+//
+// Example:
+//
+//	store, err := llm.NewDirCache(".cache/llm")
+//	if err != nil {
+//	    return err
+//	}
+//	provider, _ := llm.Get("openai")
+//	provider = llm.WithCache(provider, store, 7*24*time.Hour)
+//
+//	result, err := provider.Chat(ctx, opts)
+//	if result.Cached {
+//	    // not billed
+//	}
+func WithCache(provider Provider, store CacheStore, ttl time.Duration) Provider {
+	return &cachedProvider{Provider: provider, store: store, ttl: ttl}
+}
+
+// cachedProvider caches the results of the wrapped provider.
+type cachedProvider struct {
+	Provider
+	store CacheStore
+	ttl   time.Duration
+}
+
+// Chat returns the cached result of the request, or performs LLM chat
+// completion and caches its result.
+func (p *cachedProvider) Chat(ctx context.Context, opts *Options) (*StandardResult, error) {
+	key := CacheKey(p.Name(), opts)
+	if !cacheBypassed(ctx) {
+		if result, ok := p.lookup(key); ok {
+			return result, nil
+		}
+	}
+
+	result, err := p.Provider.Chat(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	p.save(key, opts, result)
+	return result, nil
+}
+
+// ChatStream returns the cached result of the request as a single chunk,
+// or opens a stream whose result is cached once it is read to the end.
+func (p *cachedProvider) ChatStream(ctx context.Context, opts *Options) (*Stream, error) {
+	streamer, ok := p.Provider.(StreamProvider)
+	if !ok {
+		result, err := p.Chat(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		return resultStream(result), nil
+	}
+
+	key := CacheKey(p.Name(), opts)
+	if !cacheBypassed(ctx) {
+		if result, ok := p.lookup(key); ok {
+			stream := resultStream(result)
+			stream.result.Cached = true
+			return stream, nil
+		}
+	}
+
+	stream, err := streamer.ChatStream(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	stream.onComplete(func(result *StandardResult) {
+		p.save(key, opts, result)
+	})
+	return stream, nil
+}
+
+// Unwrap returns the wrapped provider.
+func (p *cachedProvider) Unwrap() Provider {
+	return p.Provider
+}
+
+// lookup returns the cached result for key, if it has not expired.
+func (p *cachedProvider) lookup(key string) (*StandardResult, bool) {
+	data, ok, err := p.store.Get(key)
+	if err != nil || !ok {
+		return nil, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Result == nil {
+		return nil, false
+	}
+	if !entry.ExpiresAt.IsZero() && time.Now().After(entry.ExpiresAt) {
+		p.store.Delete(key)
+		return nil, false
+	}
+
+	entry.Result.Cached = true
+	return entry.Result, true
+}
+
+// save caches result for key, unless it does not conform to the schema of
+// opts.
+func (p *cachedProvider) save(key string, opts *Options, result *StandardResult) {
+	if checkOutput(opts, result) != nil {
+		return
+	}
+
+	entry := cacheEntry{Result: result}
+	if p.ttl > 0 {
+		entry.ExpiresAt = time.Now().Add(p.ttl)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	p.store.Set(key, data)
+}
+
+// MemoryCache is an in-memory CacheStore that evicts the least recently
+// used entries beyond its capacity.
+type MemoryCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+// memoryEntry is an element of the MemoryCache LRU list.
+type memoryEntry struct {
+	key   string
+	value []byte
+}
+
+// Ensure the stores implement the CacheStore interface at compile time.
+var (
+	_ CacheStore = (*MemoryCache)(nil)
+	_ CacheStore = (*DirCache)(nil)
+)
+
+// NewMemoryCache creates an in-memory cache holding at most capacity
+// entries. A capacity of 0 or less means no limit.
+func NewMemoryCache(capacity int) *MemoryCache {
+	return &MemoryCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Get returns the value stored for key, or false if there is none.
+func (c *MemoryCache) Get(key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*memoryEntry).value, true, nil
+}
+
+// Set stores value for key, evicting the least recently used entry if the
+// cache is full.
+func (c *MemoryCache) Set(key string, value []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*memoryEntry).value = value
+		c.order.MoveToFront(elem)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(&memoryEntry{key: key, value: value})
+	if c.capacity > 0 && c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryEntry).key)
+	}
+	return nil
+}
+
+// Delete removes the value stored for key, if any.
+func (c *MemoryCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+	return nil
+}
+
+// Len returns the number of entries in the cache.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// DirCache is a CacheStore that keeps one file per entry in a directory,
+// so that cached responses survive between runs.
+//
+// Entries are written atomically, so a DirCache may be shared by several
+// processes.
+type DirCache struct {
+	dir string
+}
+
+// NewDirCache creates a directory cache in dir, creating it if needed.
+func NewDirCache(dir string) (*DirCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &DirCache{dir: dir}, nil
+}
+
+// Get returns the value stored for key, or false if there is none.
+func (c *DirCache) Get(key string) ([]byte, bool, error) {
+	data, err := os.ReadFile(c.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// Set stores value for key, replacing any previous value.
+func (c *DirCache) Set(key string, value []byte) error {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Delete removes the value stored for key, if any.
+func (c *DirCache) Delete(key string) error {
+	err := os.Remove(c.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// path returns the file of key, sharded by its first two characters to
+// keep directories small.
+func (c *DirCache) path(key string) string {
+	if len(key) < 2 {
+		return filepath.Join(c.dir, key+".json")
+	}
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
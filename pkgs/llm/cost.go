@@ -0,0 +1,326 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Price is the price of a model in USD per million tokens.
+type Price struct {
+	// Input is the price of prompt tokens.
+	Input float64
+
+	// Output is the price of completion tokens.
+	Output float64
+
+	// CachedInput is the price of prompt tokens read from the prompt
+	// cache. If 0, cached tokens are billed at the Input price.
+	CachedInput float64
+}
+
+// Cost returns the cost of usage in USD.
+func (p Price) Cost(usage Usage) float64 {
+	cachedPrice := p.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = p.Input
+	}
+	uncached := usage.PromptTokens - usage.CachedPromptTokens
+	cost := float64(uncached)*p.Input +
+		float64(usage.CachedPromptTokens)*cachedPrice +
+		float64(usage.CompletionTokens)*p.Output
+	return cost / 1e6
+}
+
+// priceRegistry holds the prices of models by provider.
+//
+// The built-in prices are the list prices at the time of writing; use
+// RegisterPrice to keep them current or to price other models.
+var priceRegistry = struct {
+	mu     sync.RWMutex
+	prices map[string]map[string]Price
+}{
+	prices: map[string]map[string]Price{
+		"openai": {
+			"gpt-3.5-turbo": {Input: 0.5, Output: 1.5},
+			"gpt-4":         {Input: 30, Output: 60},
+			"gpt-4-turbo":   {Input: 10, Output: 30},
+			"gpt-4o":        {Input: 2.5, Output: 10, CachedInput: 1.25},
+			"gpt-4o-mini":   {Input: 0.15, Output: 0.6, CachedInput: 0.075},
+			"gpt-4.1":       {Input: 2, Output: 8, CachedInput: 0.5},
+			"gpt-4.1-mini":  {Input: 0.4, Output: 1.6, CachedInput: 0.1},
+			"gpt-4.1-nano":  {Input: 0.1, Output: 0.4, CachedInput: 0.025},
+			"o1":            {Input: 15, Output: 60, CachedInput: 7.5},
+			"o3-mini":       {Input: 1.1, Output: 4.4, CachedInput: 0.55},
+			"o4-mini":       {Input: 1.1, Output: 4.4, CachedInput: 0.275},
+		},
+		"claude": {
+			"claude-3-haiku":    {Input: 0.25, Output: 1.25, CachedInput: 0.03},
+			"claude-3-opus":     {Input: 15, Output: 75, CachedInput: 1.5},
+			"claude-3-5-haiku":  {Input: 0.8, Output: 4, CachedInput: 0.08},
+			"claude-3-5-sonnet": {Input: 3, Output: 15, CachedInput: 0.3},
+			"claude-3-7-sonnet": {Input: 3, Output: 15, CachedInput: 0.3},
+			"claude-sonnet-4":   {Input: 3, Output: 15, CachedInput: 0.3},
+			"claude-opus-4":     {Input: 15, Output: 75, CachedInput: 1.5},
+		},
+		"gemini": {
+			"gemini-1.5-flash": {Input: 0.075, Output: 0.3, CachedInput: 0.01875},
+			"gemini-1.5-pro":   {Input: 1.25, Output: 5, CachedInput: 0.3125},
+			"gemini-2.0-flash": {Input: 0.1, Output: 0.4, CachedInput: 0.025},
+			"gemini-2.5-flash": {Input: 0.3, Output: 2.5, CachedInput: 0.075},
+			"gemini-2.5-pro":   {Input: 1.25, Output: 10, CachedInput: 0.31},
+		},
+		// Local models are free
+		"ollama": {
+			"": {},
+		},
+	},
+}
+
+// RegisterPrice sets the price of a model of a provider.
+//
+// model may be a prefix, as for RegisterModel; an empty model prices every
+// model of the provider. This function is safe for concurrent use.
+func RegisterPrice(provider, model string, price Price) {
+	priceRegistry.mu.Lock()
+	defer priceRegistry.mu.Unlock()
+
+	models, ok := priceRegistry.prices[provider]
+	if !ok {
+		models = make(map[string]Price)
+		priceRegistry.prices[provider] = models
+	}
+	models[model] = price
+}
+
+// LookupPrice returns the price of a model of a provider, matching the
+// longest registered prefix of the model name.
+//
+// Returns false if the model has no price.
+func LookupPrice(provider, model string) (Price, bool) {
+	priceRegistry.mu.RLock()
+	defer priceRegistry.mu.RUnlock()
+	return longestPrefix(priceRegistry.prices[provider], model)
+}
+
+// ledgerKey is the context key of ContextWithLedger.
+type ledgerKey struct{}
+
+// ContextWithLedger returns a context that records the usage of the calls
+// made with it in ledger.
+//
+// Calls made with Chat, ChatStream, ChatWithRetry, ChatWithTools and
+// Toolbox.Run are recorded; ChatStream records a stream when it is closed.
+func ContextWithLedger(ctx context.Context, ledger *Ledger) context.Context {
+	return context.WithValue(ctx, ledgerKey{}, ledger)
+}
+
+// LedgerFromContext returns the ledger attached to ctx, or nil.
+func LedgerFromContext(ctx context.Context) *Ledger {
+	ledger, _ := ctx.Value(ledgerKey{}).(*Ledger)
+	return ledger
+}
+
+// recordUsage records the result of a call in the ledger of ctx, if any.
+func recordUsage(ctx context.Context, provider string, opts *Options, result *StandardResult) {
+	ledger := LedgerFromContext(ctx)
+	if ledger == nil || result == nil {
+		return
+	}
+	model := result.Model
+	if model == "" {
+		model = opts.Model
+	}
+	ledger.Add(provider, model, result.Usage, result.Cached)
+}
+
+// Ledger accumulates the usage and cost of the LLM calls of a job, such as
+// the translation of one episode.
+//
+// A Ledger is safe for concurrent use.
+//
+// Example:
+//
+//	ledger := llm.NewLedger("S01E03")
+//	ctx = llm.ContextWithLedger(ctx, ledger)
+//
+//	// ... translate the episode with llm.Chat(ctx, ...) ...
+//
+//	report := ledger.Report(episodeDuration)
+//	fmt.Print(report)
+//	fmt.Printf("$%.4f per minute of video\n", report.CostPerMinute)
+type Ledger struct {
+	job string
+
+	mu      sync.Mutex
+	entries map[spendKey]*ModelSpend
+}
+
+// spendKey identifies the spend of a model.
+type spendKey struct {
+	provider string
+	model    string
+}
+
+// NewLedger creates an empty ledger for a job.
+func NewLedger(job string) *Ledger {
+	return &Ledger{job: job, entries: make(map[spendKey]*ModelSpend)}
+}
+
+// Add records a call to model of provider.
+//
+// Models reported as "provider/model" by a Fallback are priced as the
+// backend that answered. Cached results are counted but cost nothing.
+func (l *Ledger) Add(provider, model string, usage Usage, cached bool) {
+	if _, ok := LookupPrice(provider, model); !ok {
+		if backend, backendModel, found := strings.Cut(model, "/"); found {
+			provider, model = backend, backendModel
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := spendKey{provider: provider, model: model}
+	spend, ok := l.entries[key]
+	if !ok {
+		spend = &ModelSpend{Provider: provider, Model: model}
+		price, priced := LookupPrice(provider, model)
+		spend.Priced = priced
+		spend.price = price
+		l.entries[key] = spend
+	}
+
+	spend.Calls++
+	if cached {
+		spend.CachedCalls++
+		return
+	}
+	spend.Usage.Add(usage)
+	spend.Cost += spend.price.Cost(usage)
+}
+
+// Report summarizes the spend of the job.
+//
+// videoDuration is the duration of the video the job processed, used for
+// the cost per minute; pass 0 if it is not known.
+func (l *Ledger) Report(videoDuration time.Duration) *SpendReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	report := &SpendReport{Job: l.job, VideoDuration: videoDuration}
+	for _, spend := range l.entries {
+		report.Models = append(report.Models, *spend)
+		report.Calls += spend.Calls
+		report.CachedCalls += spend.CachedCalls
+		report.Usage.Add(spend.Usage)
+		report.Cost += spend.Cost
+		if !spend.Priced && spend.Calls > spend.CachedCalls {
+			report.Unpriced = true
+		}
+	}
+
+	sort.Slice(report.Models, func(i, j int) bool {
+		a, b := report.Models[i], report.Models[j]
+		if a.Cost != b.Cost {
+			return a.Cost > b.Cost
+		}
+		return a.Provider+"/"+a.Model < b.Provider+"/"+b.Model
+	})
+
+	if videoDuration > 0 {
+		report.CostPerMinute = report.Cost / videoDuration.Minutes()
+	}
+	return report
+}
+
+// ModelSpend is the spend on one model.
+type ModelSpend struct {
+	// Provider is the provider name.
+	Provider string `json:"provider"`
+
+	// Model is the model name.
+	Model string `json:"model"`
+
+	// Calls is the number of calls, including cached ones.
+	Calls int `json:"calls"`
+
+	// CachedCalls is the number of calls served from a response cache.
+	CachedCalls int `json:"cached_calls,omitempty"`
+
+	// Usage is the total usage of the calls that were not cached.
+	Usage Usage `json:"usage"`
+
+	// Cost is the total cost in USD.
+	Cost float64 `json:"cost"`
+
+	// Priced reports whether the model has a price. Calls to models
+	// without a price cost 0.
+	Priced bool `json:"priced"`
+
+	price Price
+}
+
+// SpendReport is the spend of a job.
+type SpendReport struct {
+	// Job is the name of the job.
+	Job string `json:"job"`
+
+	// Models is the spend per model, most expensive first.
+	Models []ModelSpend `json:"models"`
+
+	// Calls is the number of calls, including cached ones.
+	Calls int `json:"calls"`
+
+	// CachedCalls is the number of calls served from a response cache.
+	CachedCalls int `json:"cached_calls,omitempty"`
+
+	// Usage is the total usage of the calls that were not cached.
+	Usage Usage `json:"usage"`
+
+	// Cost is the total cost in USD.
+	Cost float64 `json:"cost"`
+
+	// Unpriced reports whether some calls were made to models without a
+	// price, so that Cost is a lower bound.
+	Unpriced bool `json:"unpriced,omitempty"`
+
+	// VideoDuration is the duration of the processed video, or 0.
+	VideoDuration time.Duration `json:"video_duration,omitempty"`
+
+	// CostPerMinute is the cost in USD per minute of video, or 0 if the
+	// duration is not known.
+	CostPerMinute float64 `json:"cost_per_minute,omitempty"`
+}
+
+// String formats the report as a table.
+func (r *SpendReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Job: %s\n", r.Job)
+
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "MODEL\tCALLS\tCACHED\tPROMPT\tCACHED PROMPT\tCOMPLETION\tCOST (USD)\t")
+	for _, m := range r.Models {
+		cost := fmt.Sprintf("%.4f", m.Cost)
+		if !m.Priced {
+			cost = "n/a"
+		}
+		fmt.Fprintf(w, "%s/%s\t%d\t%d\t%d\t%d\t%d\t%s\t\n", m.Provider, m.Model, m.Calls, m.CachedCalls,
+			m.Usage.PromptTokens, m.Usage.CachedPromptTokens, m.Usage.CompletionTokens, cost)
+	}
+	fmt.Fprintf(w, "TOTAL\t%d\t%d\t%d\t%d\t%d\t%.4f\t\n", r.Calls, r.CachedCalls,
+		r.Usage.PromptTokens, r.Usage.CachedPromptTokens, r.Usage.CompletionTokens, r.Cost)
+	w.Flush()
+
+	if r.Unpriced {
+		b.WriteString("Some models have no price; the total is a lower bound.\n")
+	}
+	if r.VideoDuration > 0 {
+		fmt.Fprintf(&b, "Video: %s, %.4f USD per minute\n", r.VideoDuration.Round(time.Second), r.CostPerMinute)
+	}
+	return b.String()
+}
@@ -0,0 +1,214 @@
+package llm
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Error kinds reported by APIError.
+//
+// Use errors.Is to check the kind of an error returned by a provider:
+//
+//	if errors.Is(err, llm.ErrRateLimited) {
+//	    // slow down
+//	}
+var (
+	// ErrRateLimited means the request was rejected by a rate limit (HTTP 429).
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrOverloaded means the service is temporarily overloaded (HTTP 503, 529).
+	ErrOverloaded = errors.New("overloaded")
+
+	// ErrServer means the service failed to handle the request (HTTP 5xx).
+	ErrServer = errors.New("server error")
+
+	// ErrAuth means the API key is missing, invalid or lacks permission
+	// (HTTP 401, 403).
+	ErrAuth = errors.New("authentication failed")
+
+	// ErrInvalidRequest means the request was rejected as malformed (HTTP 4xx).
+	ErrInvalidRequest = errors.New("invalid request")
+
+	// ErrContextLength means the prompt and requested output do not fit in
+	// the model's context window.
+	ErrContextLength = errors.New("context length exceeded")
+)
+
+// contextLengthMarkers are phrases used by providers to report that the
+// context window was exceeded.
+var contextLengthMarkers = []string{
+	"context_length_exceeded",
+	"maximum context length",
+	"context window",
+	"prompt is too long",
+	"too many tokens",
+	"exceeds the maximum number of tokens",
+	"input token count",
+}
+
+// APIError represents an error response from an LLM provider.
+type APIError struct {
+	// Provider is the name of the provider that returned the error.
+	Provider string
+
+	// StatusCode is the HTTP status code, or 0 for errors reported
+	// inside a stream.
+	StatusCode int
+
+	// Response is the response body or error message.
+	Response string
+
+	// Kind is one of the Err* error kinds, or nil if the error could not
+	// be classified.
+	Kind error
+
+	// RetryAfter is the delay requested by the Retry-After header,
+	// or 0 if none was sent.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	kind := "unknown error"
+	if e.Kind != nil {
+		kind = e.Kind.Error()
+	}
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%s API error (%s): %s", e.Provider, kind, e.Response)
+	}
+	return fmt.Sprintf("%s API error (status %d, %s): %s", e.Provider, e.StatusCode, kind, e.Response)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Kind
+}
+
+// NewAPIError creates an APIError from an HTTP error response.
+//
+// The body of resp is read and closed. This function is intended for
+// provider implementations.
+func NewAPIError(provider string, resp *http.Response) *APIError {
+	body, _ := readBody(resp)
+	return &APIError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Response:   body,
+		Kind:       classify(resp.StatusCode, body),
+		RetryAfter: parseRetryAfter(resp.Header, time.Now()),
+	}
+}
+
+// NewStreamError creates an APIError from an error event received in a
+// stream, classifying it by its type and message (e.g., "overloaded_error").
+//
+// This function is intended for provider implementations.
+func NewStreamError(provider, errorType, message string) *APIError {
+	response := message
+	if errorType != "" {
+		response = errorType + ": " + message
+	}
+	return &APIError{
+		Provider: provider,
+		Response: response,
+		Kind:     classify(0, response),
+	}
+}
+
+// IsRetryable reports whether a request that failed with err may succeed
+// when retried: rate limits, overloads, server errors and transient network
+// errors (timeouts, reset or refused connections and truncated responses).
+//
+// Errors caused by the caller's context being canceled or timing out are
+// never retryable, nor are TLS certificate errors.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, ErrRateLimited) || errors.Is(err, ErrOverloaded) || errors.Is(err, ErrServer) {
+		return true
+	}
+
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCert x509.CertificateInvalidError
+	if errors.As(err, &certErr) || errors.As(err, &unknownAuthority) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidCert) {
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// classify returns the error kind of a response.
+func classify(status int, body string) error {
+	lower := strings.ToLower(body)
+	if status != http.StatusTooManyRequests && status < 500 {
+		for _, marker := range contextLengthMarkers {
+			if strings.Contains(lower, marker) {
+				return ErrContextLength
+			}
+		}
+	}
+
+	switch {
+	case status == http.StatusTooManyRequests || strings.Contains(lower, "rate_limit") ||
+		strings.Contains(lower, "resource_exhausted"):
+		return ErrRateLimited
+	case status == http.StatusServiceUnavailable || status == 529 || strings.Contains(lower, "overloaded"):
+		return ErrOverloaded
+	case status == http.StatusUnauthorized || status == http.StatusForbidden ||
+		strings.Contains(lower, "authentication_error") || strings.Contains(lower, "permission_error"):
+		return ErrAuth
+	case status == http.StatusRequestEntityTooLarge:
+		return ErrContextLength
+	case status >= 500 || strings.Contains(lower, "api_error"):
+		return ErrServer
+	case status >= 400 || strings.Contains(lower, "invalid_request"):
+		return ErrInvalidRequest
+	}
+	return nil
+}
+
+// parseRetryAfter returns the delay requested by the retry-after-ms or
+// Retry-After headers, or 0 if none was sent.
+func parseRetryAfter(header http.Header, now time.Time) time.Duration {
+	if ms, err := strconv.ParseFloat(header.Get("retry-after-ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// readBody reads and closes the body of resp.
+func readBody(resp *http.Response) (string, error) {
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
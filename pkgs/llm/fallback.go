@@ -0,0 +1,217 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// FallbackEntry is one backend of a Fallback provider.
+type FallbackEntry struct {
+	// Provider is the backend to call. It may itself be wrapped, e.g. with
+	// WithRetry or WithLimiter.
+	Provider Provider
+
+	// Model replaces the requested model for this backend.
+	// If empty, the requested model is used.
+	Model string
+
+	// Options overrides the request options for this backend. Non-zero
+	// fields replace the requested values: BaseURL, APIKey, Temperature,
+	// MaxTokens, TopP, Stop, SystemPrompt and HTTPClient. Extra is merged
+	// into the requested Extra. Optional.
+	Options *Options
+}
+
+// Fallback is a composite provider that tries its backends in order,
+// moving to the next one when a call fails with a retryable error.
+//
+// Register it under its own name to use it like any other provider:
+//
+//	claude, _ := llm.Get("claude")
+//	gemini, _ := llm.Get("gemini")
+//	openai, _ := llm.Get("openai")
+//
+//	llm.Register(llm.NewFallback("resilient",
+//	    llm.FallbackEntry{Provider: claude, Model: "claude-3-5-sonnet-latest", Options: &llm.Options{APIKey: claudeKey}},
+//	    llm.FallbackEntry{Provider: gemini, Model: "gemini-1.5-pro", Options: &llm.Options{APIKey: geminiKey}},
+//	    llm.FallbackEntry{Provider: openai, Model: "gpt-4o", Options: &llm.Options{APIKey: openaiKey, BaseURL: compatURL}},
+//	))
+//
+//	result, err := llm.Chat(ctx, "resilient", opts)
+//	fmt.Println(result.Model) // e.g. "gemini/gemini-1.5-pro-002"
+//
+// The Model of the result is the name of the backend that answered and the
+// model it reported, joined with "/".
+type Fallback struct {
+	// ShouldFallback decides whether an error moves on to the next backend.
+	// Default: IsRetryable
+	ShouldFallback func(error) bool
+
+	name    string
+	entries []FallbackEntry
+}
+
+// Ensure Fallback implements the StreamProvider and OptionsValidator
+// interfaces at compile time.
+var (
+	_ StreamProvider   = (*Fallback)(nil)
+	_ OptionsValidator = (*Fallback)(nil)
+)
+
+// NewFallback creates a fallback provider registered as name, trying
+// entries in order.
+func NewFallback(name string, entries ...FallbackEntry) *Fallback {
+	return &Fallback{name: name, entries: entries}
+}
+
+// Name returns the provider's unique identifier.
+func (f *Fallback) Name() string {
+	return f.name
+}
+
+// ValidateOptions validates the options as they are sent to each backend,
+// so that the API key and model may be set per entry.
+func (f *Fallback) ValidateOptions(opts *Options) error {
+	if len(f.entries) == 0 {
+		return &ValidationError{Field: "Provider", Message: fmt.Sprintf("fallback %q has no entries", f.name)}
+	}
+	for _, entry := range f.entries {
+		entryOpts := entry.options(opts)
+		if err := validateFor(entry.Provider, entryOpts); err != nil {
+			return fmt.Errorf("fallback entry %s: %w", entry.Provider.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Chat performs LLM chat completion with the first backend that succeeds.
+//
+// Returns the error of the first backend that fails with an error that
+// does not allow falling back, or all the errors if every backend failed.
+func (f *Fallback) Chat(ctx context.Context, opts *Options) (*StandardResult, error) {
+	var errs []error
+	for _, entry := range f.entries {
+		entryOpts := entry.options(opts)
+		result, err := entry.Provider.Chat(ctx, entryOpts)
+		if err == nil {
+			result.Model = entry.backend(result.Model, entryOpts)
+			return result, nil
+		}
+		if !f.shouldFallback(err) {
+			return nil, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", entry.Provider.Name(), err))
+	}
+	return nil, f.exhausted(errs)
+}
+
+// ChatStream opens a stream with the first backend that succeeds.
+//
+// Only errors while opening the stream fall back; errors in the middle of
+// a stream are returned to the caller.
+func (f *Fallback) ChatStream(ctx context.Context, opts *Options) (*Stream, error) {
+	var errs []error
+	for _, entry := range f.entries {
+		entryOpts := entry.options(opts)
+
+		var stream *Stream
+		var err error
+		if streamer, ok := entry.Provider.(StreamProvider); ok {
+			stream, err = streamer.ChatStream(ctx, entryOpts)
+		} else {
+			var result *StandardResult
+			if result, err = entry.Provider.Chat(ctx, entryOpts); err == nil {
+				stream = resultStream(result)
+			}
+		}
+
+		if err == nil {
+			stream.result.Model = entry.backend("", entryOpts)
+			recv := stream.recv
+			stream.recv = func() (*StreamChunk, error) {
+				chunk, err := recv()
+				if chunk != nil && chunk.Model != "" {
+					chunk.Model = entry.backend(chunk.Model, entryOpts)
+				}
+				return chunk, err
+			}
+			return stream, nil
+		}
+		if !f.shouldFallback(err) {
+			return nil, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", entry.Provider.Name(), err))
+	}
+	return nil, f.exhausted(errs)
+}
+
+// shouldFallback reports whether err moves on to the next backend.
+func (f *Fallback) shouldFallback(err error) bool {
+	if f.ShouldFallback != nil {
+		return f.ShouldFallback(err)
+	}
+	return IsRetryable(err)
+}
+
+// exhausted returns the error reported when every backend failed.
+func (f *Fallback) exhausted(errs []error) error {
+	return fmt.Errorf("all %d fallback backends failed: %w", len(errs), errors.Join(errs...))
+}
+
+// options returns the options sent to the backend of the entry.
+func (e *FallbackEntry) options(opts *Options) *Options {
+	result := *opts
+	if e.Model != "" {
+		result.Model = e.Model
+	}
+
+	o := e.Options
+	if o == nil {
+		return &result
+	}
+	if o.BaseURL != "" {
+		result.BaseURL = o.BaseURL
+	}
+	if o.APIKey != "" {
+		result.APIKey = o.APIKey
+	}
+	if o.HTTPClient != nil {
+		result.HTTPClient = o.HTTPClient
+	}
+	if o.Temperature != 0 {
+		result.Temperature = o.Temperature
+	}
+	if o.MaxTokens != 0 {
+		result.MaxTokens = o.MaxTokens
+	}
+	if o.TopP != 0 {
+		result.TopP = o.TopP
+	}
+	if len(o.Stop) > 0 {
+		result.Stop = o.Stop
+	}
+	if o.SystemPrompt != "" {
+		result.SystemPrompt = o.SystemPrompt
+	}
+	if len(o.Extra) > 0 {
+		extra := make(map[string]interface{}, len(opts.Extra)+len(o.Extra))
+		for k, v := range opts.Extra {
+			extra[k] = v
+		}
+		for k, v := range o.Extra {
+			extra[k] = v
+		}
+		result.Extra = extra
+	}
+	return &result
+}
+
+// backend returns the "provider/model" name of the backend that answered,
+// using the requested model if none was reported.
+func (e *FallbackEntry) backend(model string, opts *Options) string {
+	if model == "" {
+		model = opts.Model
+	}
+	return e.Provider.Name() + "/" + model
+}
@@ -0,0 +1,80 @@
+// Package sse reads server-sent event streams, as used by the streaming
+// endpoints of the LLM providers.
+package sse
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// maxLineSize is the maximum size of a single line in the stream.
+const maxLineSize = 4 * 1024 * 1024
+
+// Event is a single server-sent event.
+type Event struct {
+	// Name is the event type, or "" for unnamed events.
+	Name string
+
+	// Data is the event payload. Multiple data lines are joined with "\n".
+	Data string
+}
+
+// Reader reads events from a server-sent event stream.
+type Reader struct {
+	scanner *bufio.Scanner
+}
+
+// NewReader creates a reader over r.
+func NewReader(r io.Reader) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return &Reader{scanner: scanner}
+}
+
+// Next returns the next event with data.
+//
+// Comments and events without data are skipped.
+// Returns io.EOF at the end of the stream.
+func (r *Reader) Next() (*Event, error) {
+	event := &Event{}
+	var data []string
+
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+
+		if line == "" {
+			// A blank line dispatches the event
+			if len(data) > 0 {
+				event.Data = strings.Join(data, "\n")
+				return event, nil
+			}
+			event = &Event{}
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Name = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Dispatch a final event that is not followed by a blank line
+	if len(data) > 0 {
+		event.Data = strings.Join(data, "\n")
+		return event, nil
+	}
+	return nil, io.EOF
+}
@@ -0,0 +1,388 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// limitWindow is the sliding window over which request and token rates
+// are measured.
+const limitWindow = time.Minute
+
+// Limits caps the rate and concurrency of calls to a provider or model.
+//
+// A zero field means no limit.
+type Limits struct {
+	// RequestsPerMinute is the maximum number of requests started in any
+	// one-minute window.
+	RequestsPerMinute int
+
+	// TokensPerMinute is the maximum number of tokens used in any
+	// one-minute window. Tokens are accounted from StandardResult.Usage
+	// when each call completes, so a burst of concurrent calls may
+	// overshoot the limit by the size of the calls in flight.
+	TokensPerMinute int
+
+	// MaxConcurrent is the maximum number of requests in flight.
+	MaxConcurrent int
+}
+
+// Validate validates the limits.
+func (l *Limits) Validate() error {
+	if l.RequestsPerMinute < 0 {
+		return &ValidationError{Field: "RequestsPerMinute", Message: "must be non-negative"}
+	}
+	if l.TokensPerMinute < 0 {
+		return &ValidationError{Field: "TokensPerMinute", Message: "must be non-negative"}
+	}
+	if l.MaxConcurrent < 0 {
+		return &ValidationError{Field: "MaxConcurrent", Message: "must be non-negative"}
+	}
+	return nil
+}
+
+// Limiter enforces client-side rate and concurrency limits for LLM calls.
+//
+// Limits are configured per provider name and, optionally, per model.
+// Provider-wide limits are shared by all models of the provider; when both
+// are set, a call must satisfy both.
+//
+// A Limiter is safe for concurrent use. Share one Limiter across all the
+// goroutines of a batch job so that the whole job respects one budget:
+//
+//	limiter := llm.NewLimiter()
+//	limiter.SetLimits("openai", "", llm.Limits{RequestsPerMinute: 500})
+//	limiter.SetLimits("openai", "gpt-4o", llm.Limits{TokensPerMinute: 30000, MaxConcurrent: 8})
+//
+//	provider, _ := llm.Get("openai")
+//	provider = llm.WithLimiter(provider, limiter)
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[limitKey]*bucket
+}
+
+// limitKey identifies the limits of a provider ("" model) or of a model.
+type limitKey struct {
+	provider string
+	model    string
+}
+
+// NewLimiter creates a limiter with no limits configured.
+func NewLimiter() *Limiter {
+	return &Limiter{buckets: make(map[limitKey]*bucket)}
+}
+
+// SetLimits sets the limits of a provider, or of one of its models if model
+// is not empty.
+//
+// Replacing the limits keeps the requests and tokens already accounted.
+func (l *Limiter) SetLimits(provider, model string, limits Limits) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := limitKey{provider: provider, model: model}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{changed: make(chan struct{})}
+		l.buckets[key] = b
+	}
+
+	b.mu.Lock()
+	b.limits = limits
+	b.notify()
+	b.mu.Unlock()
+	return nil
+}
+
+// Acquire waits until a call to model of provider is allowed to start.
+//
+// The returned release function must be called exactly once when the call
+// completes, with the usage it reported (or a zero Usage if it failed).
+// Returns the context error if ctx is done while waiting.
+func (l *Limiter) Acquire(ctx context.Context, provider, model string) (release func(Usage), err error) {
+	buckets := l.lookup(provider, model)
+
+	acquired := make([]*bucket, 0, len(buckets))
+	starts := make([]time.Time, 0, len(buckets))
+	for _, b := range buckets {
+		start, err := b.acquire(ctx)
+		if err != nil {
+			// The call never started: give back the slots already taken
+			for i, a := range acquired {
+				a.cancel(starts[i])
+			}
+			return nil, fmt.Errorf("rate limiter: %w", err)
+		}
+		acquired = append(acquired, b)
+		starts = append(starts, start)
+	}
+
+	var once sync.Once
+	return func(usage Usage) {
+		once.Do(func() {
+			for _, b := range acquired {
+				b.release(usage)
+			}
+		})
+	}, nil
+}
+
+// lookup returns the buckets that apply to a call, provider-wide first.
+func (l *Limiter) lookup(provider, model string) []*bucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var buckets []*bucket
+	if b, ok := l.buckets[limitKey{provider: provider}]; ok {
+		buckets = append(buckets, b)
+	}
+	if model != "" {
+		if b, ok := l.buckets[limitKey{provider: provider, model: model}]; ok {
+			buckets = append(buckets, b)
+		}
+	}
+	return buckets
+}
+
+// bucket tracks the requests, tokens and calls in flight under one set of
+// limits.
+type bucket struct {
+	mu       sync.Mutex
+	limits   Limits
+	requests []time.Time
+	tokens   []tokenRecord
+	inFlight int
+
+	// changed is closed and replaced whenever a waiting call may be able
+	// to proceed.
+	changed chan struct{}
+}
+
+// tokenRecord is the usage of a completed call.
+type tokenRecord struct {
+	at     time.Time
+	tokens int
+}
+
+// acquire waits until the bucket admits a call, then accounts its start.
+// Returns the time the start was accounted at.
+func (b *bucket) acquire(ctx context.Context) (time.Time, error) {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		wait, ok := b.admit(now)
+		if ok {
+			b.requests = append(b.requests, now)
+			b.inFlight++
+			b.mu.Unlock()
+			return now, nil
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			err := ctx.Err()
+			if timer != nil {
+				timer.Stop()
+			}
+			return time.Time{}, err
+		case <-changed:
+		case <-timeout:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// admit reports whether a call may start now. If not, it returns how long
+// to wait before checking again, or 0 to wait for a call to complete.
+//
+// The caller must hold b.mu.
+func (b *bucket) admit(now time.Time) (time.Duration, bool) {
+	b.expire(now)
+
+	if b.limits.MaxConcurrent > 0 && b.inFlight >= b.limits.MaxConcurrent {
+		return 0, false
+	}
+
+	var wait time.Duration
+	if b.limits.RequestsPerMinute > 0 && len(b.requests) >= b.limits.RequestsPerMinute {
+		// Wait until enough of the oldest requests leave the window
+		oldest := b.requests[len(b.requests)-b.limits.RequestsPerMinute]
+		wait = max(wait, oldest.Add(limitWindow).Sub(now))
+	}
+
+	if b.limits.TokensPerMinute > 0 {
+		used := 0
+		for _, r := range b.tokens {
+			used += r.tokens
+		}
+		for _, r := range b.tokens {
+			if used < b.limits.TokensPerMinute {
+				break
+			}
+			// Wait until this record leaves the window
+			used -= r.tokens
+			wait = max(wait, r.at.Add(limitWindow).Sub(now))
+		}
+	}
+
+	if wait > 0 {
+		return wait, false
+	}
+	return 0, true
+}
+
+// expire drops the requests and tokens that left the window.
+//
+// The caller must hold b.mu.
+func (b *bucket) expire(now time.Time) {
+	cutoff := now.Add(-limitWindow)
+
+	i := 0
+	for i < len(b.requests) && !b.requests[i].After(cutoff) {
+		i++
+	}
+	b.requests = b.requests[i:]
+
+	i = 0
+	for i < len(b.tokens) && !b.tokens[i].at.After(cutoff) {
+		i++
+	}
+	b.tokens = b.tokens[i:]
+}
+
+// release accounts the completion of a call.
+func (b *bucket) release(usage Usage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.inFlight--
+	tokens := usage.TotalTokens
+	if tokens == 0 {
+		tokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	if tokens > 0 {
+		b.tokens = append(b.tokens, tokenRecord{at: time.Now(), tokens: tokens})
+	}
+	b.notify()
+}
+
+// cancel undoes the acquire of a call that never started, accounted at
+// start: the call is no longer in flight and its request is removed.
+func (b *bucket) cancel(start time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.inFlight--
+	for i := len(b.requests) - 1; i >= 0; i-- {
+		if b.requests[i].Equal(start) {
+			b.requests = append(b.requests[:i], b.requests[i+1:]...)
+			break
+		}
+	}
+	b.notify()
+}
+
+// notify wakes the calls waiting on the bucket.
+//
+// The caller must hold b.mu.
+func (b *bucket) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// WithLimiter wraps a provider so that its calls wait for the limits of
+// limiter, keyed by the provider's name and the requested model.
+//
+// Streams returned by ChatStream hold their slot until they are closed,
+// and their usage is accounted from the complete response.
+func WithLimiter(provider Provider, limiter *Limiter) Provider {
+	return &limitedProvider{Provider: provider, limiter: limiter}
+}
+
+// limitedProvider limits the calls of the wrapped provider.
+type limitedProvider struct {
+	Provider
+	limiter *Limiter
+}
+
+// Chat performs LLM chat completion once the limits allow it.
+func (p *limitedProvider) Chat(ctx context.Context, opts *Options) (*StandardResult, error) {
+	release, err := p.limiter.Acquire(ctx, p.Name(), opts.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := p.Provider.Chat(ctx, opts)
+	if err != nil {
+		release(Usage{})
+		return nil, err
+	}
+	release(result.Usage)
+	return result, nil
+}
+
+// Unwrap returns the wrapped provider.
+func (p *limitedProvider) Unwrap() Provider {
+	return p.Provider
+}
+
+// ChatStream opens a stream once the limits allow it.
+func (p *limitedProvider) ChatStream(ctx context.Context, opts *Options) (*Stream, error) {
+	streamer, ok := p.Provider.(StreamProvider)
+	if !ok {
+		result, err := p.Chat(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		return resultStream(result), nil
+	}
+
+	release, err := p.limiter.Acquire(ctx, p.Name(), opts.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := streamer.ChatStream(ctx, opts)
+	if err != nil {
+		release(Usage{})
+		return nil, err
+	}
+	stream.closer = &releaseCloser{closer: stream.closer, release: func() {
+		release(stream.Result().Usage)
+	}}
+	return stream, nil
+}
+
+// releaseCloser calls release when a stream is closed, e.g. to free a
+// limiter slot.
+type releaseCloser struct {
+	closer  io.Closer
+	release func()
+}
+
+// Close calls release and closes the underlying stream.
+func (c *releaseCloser) Close() error {
+	c.release()
+	if c.closer == nil {
+		return nil
+	}
+	return c.closer.Close()
+}
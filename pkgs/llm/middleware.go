@@ -0,0 +1,166 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Middleware wraps a provider to add behavior around its calls, such as
+// retries, caching, logging, redaction or metrics.
+//
+// The returned provider should implement StreamProvider if next may
+// stream, and Unwrap so that the capabilities of next (OptionsValidator,
+// APIKeyPolicy, ModelLister) are still found. The decorators of this
+// package, such as WithRetry and WithCache, do both.
+//
+// Example:
+//
+//	llm.Use(func(next llm.Provider) llm.Provider {
+//	    return llm.WithRetry(next, nil)
+//	})
+//	llm.UseFor("openai", func(next llm.Provider) llm.Provider {
+//	    return llm.WithCache(next, store, 24*time.Hour)
+//	})
+//
+//	// Calls to "openai" are now cached, then retried
+//	result, err := llm.Chat(ctx, "openai", opts)
+type Middleware func(next Provider) Provider
+
+// Wrapper is implemented by providers that wrap another provider.
+type Wrapper interface {
+	// Unwrap returns the wrapped provider.
+	Unwrap() Provider
+}
+
+// Chain wraps provider with middlewares. The first middleware is the
+// outermost: Chain(p, a, b) returns a(b(p)).
+func Chain(provider Provider, middlewares ...Middleware) Provider {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		provider = middlewares[i](provider)
+	}
+	return provider
+}
+
+// Use installs middlewares for every provider of the global registry.
+//
+// See Registry.Use. This function is safe for concurrent use.
+func Use(middlewares ...Middleware) {
+	globalRegistry.Use(middlewares...)
+}
+
+// UseFor installs middlewares for one provider of the global registry.
+//
+// See Registry.UseFor. This function is safe for concurrent use.
+func UseFor(name string, middlewares ...Middleware) {
+	globalRegistry.UseFor(name, middlewares...)
+}
+
+// Use installs middlewares for every provider of this registry.
+//
+// Middlewares apply to the providers returned by Get, and so to Chat,
+// ChatStream and ChatWithRetry, including providers registered later.
+// They are added after the middlewares installed before, and wrap the
+// middlewares installed with UseFor. This method is safe for concurrent
+// use.
+func (r *Registry) Use(middlewares ...Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+// UseFor installs middlewares for the provider named name in this
+// registry, which need not be registered yet.
+//
+// They are added after the middlewares installed before for the same
+// provider. This method is safe for concurrent use.
+func (r *Registry) UseFor(name string, middlewares ...Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.entryMiddlewares == nil {
+		r.entryMiddlewares = make(map[string][]Middleware)
+	}
+	r.entryMiddlewares[name] = append(r.entryMiddlewares[name], middlewares...)
+}
+
+// capability returns the first provider of the chain wrapped by provider,
+// starting with provider itself, that implements T.
+func capability[T any](provider Provider) (T, bool) {
+	for {
+		if c, ok := provider.(T); ok {
+			return c, true
+		}
+		wrapper, ok := provider.(Wrapper)
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		provider = wrapper.Unwrap()
+	}
+}
+
+// WithHTTPClient returns a middleware that sends the requests of the
+// wrapped provider with client, unless their options set HTTPClient.
+//
+// Example:
+//
+//	// Route OpenAI traffic through a proxy
+//	proxyURL, _ := url.Parse("http://proxy.internal:3128")
+//	llm.UseFor("openai", llm.WithHTTPClient(&http.Client{
+//	    Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)},
+//	}))
+func WithHTTPClient(client *http.Client) Middleware {
+	return func(next Provider) Provider {
+		return &httpClientProvider{Provider: next, client: client}
+	}
+}
+
+// httpClientProvider sets the HTTP client of the calls to the wrapped
+// provider.
+type httpClientProvider struct {
+	Provider
+	client *http.Client
+}
+
+// Chat performs LLM chat completion with the HTTP client.
+func (p *httpClientProvider) Chat(ctx context.Context, opts *Options) (*StandardResult, error) {
+	return p.Provider.Chat(ctx, p.options(opts))
+}
+
+// ChatStream opens a stream with the HTTP client.
+func (p *httpClientProvider) ChatStream(ctx context.Context, opts *Options) (*Stream, error) {
+	streamer, ok := p.Provider.(StreamProvider)
+	if !ok {
+		result, err := p.Chat(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		return resultStream(result), nil
+	}
+	return streamer.ChatStream(ctx, p.options(opts))
+}
+
+// ListModels lists the models of the wrapped provider with the HTTP
+// client.
+func (p *httpClientProvider) ListModels(ctx context.Context, opts *Options) ([]ModelInfo, error) {
+	lister, ok := capability[ModelLister](p.Provider)
+	if !ok {
+		return nil, fmt.Errorf("provider '%s' cannot list models: %w", p.Name(), ErrNotSupported)
+	}
+	return lister.ListModels(ctx, p.options(opts))
+}
+
+// Unwrap returns the wrapped provider.
+func (p *httpClientProvider) Unwrap() Provider {
+	return p.Provider
+}
+
+// options returns opts with the HTTP client set.
+func (p *httpClientProvider) options(opts *Options) *Options {
+	if opts.HTTPClient != nil {
+		return opts
+	}
+	result := *opts
+	result.HTTPClient = p.client
+	return &result
+}
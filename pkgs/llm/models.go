@@ -0,0 +1,81 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotSupported means the provider does not support the requested
+// capability.
+var ErrNotSupported = errors.New("not supported")
+
+// ModelLister is implemented by providers that can list their models.
+type ModelLister interface {
+	Provider
+
+	// ListModels returns the models available with opts. Only the
+	// connection options (BaseURL, APIKey) are used.
+	ListModels(ctx context.Context, opts *Options) ([]ModelInfo, error)
+}
+
+// ModelInfo describes a model available from a provider.
+type ModelInfo struct {
+	// ID is the model identifier to use as Options.Model.
+	ID string `json:"id"`
+
+	// OwnedBy is the organization that owns the model, if reported.
+	OwnedBy string `json:"owned_by,omitempty"`
+
+	// Size is the size of the model in bytes, if reported
+	// (local servers).
+	Size int64 `json:"size,omitempty"`
+
+	// ModifiedAt is when the model was created or last updated, if reported.
+	ModifiedAt time.Time `json:"modified_at,omitzero"`
+}
+
+// ListModels returns the models of a provider in the global registry.
+//
+// Returns an error wrapping ErrNotSupported if the provider does not
+// implement ModelLister.
+//
+// Example:
+//
+//	models, err := llm.ListModels(ctx, "ollama", &llm.Options{})
+//	if err != nil {
+//	    return err
+//	}
+//	for _, m := range models {
+//	    fmt.Println(m.ID)
+//	}
+func ListModels(ctx context.Context, providerName string, opts *Options) ([]ModelInfo, error) {
+	return globalRegistry.ListModels(ctx, providerName, opts)
+}
+
+// ListModels returns the models of a provider in this registry.
+//
+// Returns an error wrapping ErrNotSupported if the provider does not
+// implement ModelLister.
+func (r *Registry) ListModels(ctx context.Context, providerName string, opts *Options) ([]ModelInfo, error) {
+	provider, err := r.Get(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	lister, ok := capability[ModelLister](provider)
+	if !ok {
+		return nil, fmt.Errorf("provider '%s' cannot list models: %w", providerName, ErrNotSupported)
+	}
+
+	if opts == nil {
+		opts = &Options{}
+	}
+	models, err := lister.ListModels(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("list models failed: %w", err)
+	}
+
+	return models, nil
+}
@@ -0,0 +1,135 @@
+package llm
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// PartType identifies the kind of content in a Part.
+type PartType string
+
+// Part types.
+const (
+	// PartText is plain text.
+	PartText PartType = "text"
+
+	// PartData is inline binary data, such as an image or an audio clip.
+	PartData PartType = "data"
+
+	// PartFile is a reference to a file by URL or provider file ID.
+	PartFile PartType = "file"
+)
+
+// Part is a piece of multimodal message content.
+//
+// Use TextPart, DataPart and FilePart to create parts. Which MIME types are
+// accepted depends on the provider and model; unsupported parts are
+// rejected when the request is built.
+type Part struct {
+	// Type is the kind of content.
+	Type PartType `json:"type"`
+
+	// Text is the content of a text part.
+	Text string `json:"text,omitempty"`
+
+	// MIMEType is the media type of a data or file part,
+	// e.g. "image/png", "audio/wav".
+	MIMEType string `json:"mime_type,omitempty"`
+
+	// Data is the content of a data part.
+	Data []byte `json:"data,omitempty"`
+
+	// URI locates the content of a file part: an http(s) URL, a provider
+	// file URI (e.g. Gemini's Files API) or a provider file ID.
+	URI string `json:"uri,omitempty"`
+}
+
+// TextPart creates a text part.
+func TextPart(text string) Part {
+	return Part{Type: PartText, Text: text}
+}
+
+// DataPart creates a part holding inline data of the given MIME type.
+func DataPart(mimeType string, data []byte) Part {
+	return Part{Type: PartData, MIMEType: mimeType, Data: data}
+}
+
+// FilePart creates a part referencing a file of the given MIME type.
+func FilePart(mimeType, uri string) Part {
+	return Part{Type: PartFile, MIMEType: mimeType, URI: uri}
+}
+
+// Base64 returns the data of the part encoded in standard base64.
+func (p Part) Base64() string {
+	return base64.StdEncoding.EncodeToString(p.Data)
+}
+
+// DataURL returns the data of the part as a data: URL.
+func (p Part) DataURL() string {
+	return "data:" + p.MIMEType + ";base64," + p.Base64()
+}
+
+// Media returns the top-level media type of the part, e.g. "image" for
+// "image/png".
+func (p Part) Media() string {
+	media, _, _ := strings.Cut(p.MIMEType, "/")
+	return media
+}
+
+// validate checks that the part has the fields its type requires.
+func (p Part) validate() error {
+	switch p.Type {
+	case PartText:
+		return nil
+	case PartData:
+		if p.MIMEType == "" {
+			return fmt.Errorf("data part requires MIMEType")
+		}
+		if len(p.Data) == 0 {
+			return fmt.Errorf("data part requires Data")
+		}
+	case PartFile:
+		if p.URI == "" {
+			return fmt.Errorf("file part requires URI")
+		}
+	default:
+		return fmt.Errorf("unknown part type %q", p.Type)
+	}
+	return nil
+}
+
+// ContentParts returns the content of the message as parts: Parts if set,
+// otherwise Content as a single text part.
+func (m Message) ContentParts() []Part {
+	if len(m.Parts) > 0 {
+		return m.Parts
+	}
+	return []Part{TextPart(m.Content)}
+}
+
+// Text returns the text content of the message: Content, or the text of
+// its text parts joined together if Parts is set.
+func (m Message) Text() string {
+	if len(m.Parts) == 0 {
+		return m.Content
+	}
+	var b strings.Builder
+	for _, part := range m.Parts {
+		if part.Type == PartText {
+			b.WriteString(part.Text)
+		}
+	}
+	return b.String()
+}
+
+// UnsupportedPartError returns the error reported when a provider cannot
+// send a part. It wraps ErrInvalidRequest. This function is intended for
+// provider implementations.
+func UnsupportedPartError(provider string, part Part) error {
+	kind := string(part.Type)
+	if part.MIMEType != "" {
+		kind += " (" + part.MIMEType + ")"
+	}
+	return fmt.Errorf("%s does not support %s message parts: %w", provider, kind, ErrInvalidRequest)
+}
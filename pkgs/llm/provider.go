@@ -29,7 +29,18 @@
 //	}
 package llm
 
-import "context"
+import (
+	"context"
+	"net/http"
+)
+
+// DefaultHTTPClient is the HTTP client used by providers when
+// Options.HTTPClient is nil.
+//
+// It has no timeout, so that long streams are not cut off; bound calls
+// with their context instead. Replace it to use a proxy, custom CAs or a
+// custom transport for every provider.
+var DefaultHTTPClient = &http.Client{}
 
 // Provider defines the interface that all LLM providers must implement.
 //
@@ -55,6 +66,22 @@ type Provider interface {
 	Chat(ctx context.Context, opts *Options) (*StandardResult, error)
 }
 
+// OptionsValidator is implemented by providers that validate options
+// themselves instead of with Options.Validate, e.g. composite providers
+// whose entries supply the API key and model.
+type OptionsValidator interface {
+	// ValidateOptions validates the options and sets default values.
+	ValidateOptions(opts *Options) error
+}
+
+// APIKeyPolicy is implemented by providers that do not always need an API
+// key, such as local servers. Options of other providers must have an
+// APIKey.
+type APIKeyPolicy interface {
+	// RequiresAPIKey reports whether a request with opts needs an API key.
+	RequiresAPIKey(opts *Options) bool
+}
+
 // Options contains unified options for LLM requests.
 //
 // These options work across all providers, though some providers may ignore
@@ -70,7 +97,7 @@ type Options struct {
 	BaseURL string
 
 	// APIKey is the authentication API key.
-	// Required by most providers.
+	// Required by most providers; optional for local servers.
 	APIKey string
 
 	// Model is the model identifier to use.
@@ -108,23 +135,62 @@ type Options struct {
 	// For others, this will be prepended as a system message.
 	SystemPrompt string
 
+	// Schema requests structured JSON output conforming to a JSON Schema.
+	// If set, the result Content holds the JSON output, which Chat
+	// validates against the schema. Optional.
+	Schema *Schema
+
+	// Tools are the tools the model may call. Optional.
+	// Calls requested by the model are returned in StandardResult.ToolCalls.
+	Tools []Tool
+
+	// ToolChoice controls whether the model calls tools:
+	// "auto" (default), "none", "required", or the name of a tool to force.
+	ToolChoice string
+
 	// Extra contains provider-specific options.
 	// Use this for parameters that are not part of the standard interface.
 	Extra map[string]interface{}
+
+	// HTTPClient is the HTTP client used for the request. To use a custom
+	// http.RoundTripper, set it as the Transport of a client.
+	// Default: DefaultHTTPClient
+	HTTPClient *http.Client
+}
+
+// Client returns the HTTP client to use for the request: HTTPClient, or
+// DefaultHTTPClient if it is nil.
+func (o *Options) Client() *http.Client {
+	if o.HTTPClient != nil {
+		return o.HTTPClient
+	}
+	return DefaultHTTPClient
 }
 
 // Message represents a single message in the conversation.
 type Message struct {
 	// Role is the message sender role.
-	// Common values: "system", "user", "assistant"
+	// Common values: "system", "user", "assistant", "tool"
 	Role string `json:"role"`
 
-	// Content is the message content.
+	// Content is the text content of the message.
+	// Ignored if Parts is set.
 	Content string `json:"content"`
 
+	// Parts is multimodal content: text, inline data such as images or
+	// audio, and file references. Optional; use Content for plain text.
+	Parts []Part `json:"parts,omitempty"`
+
 	// Name is an optional name for the message sender.
 	// Not supported by all providers.
 	Name string `json:"name,omitempty"`
+
+	// ToolCalls are the tool calls requested by an assistant message.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+
+	// ToolCallID identifies the call answered by a "tool" message, whose
+	// Content is the tool's result.
+	ToolCallID string `json:"tool_call_id,omitempty"`
 }
 
 // StandardResult represents the unified LLM completion result.
@@ -134,6 +200,9 @@ type StandardResult struct {
 	// Content is the generated text.
 	Content string `json:"content,omitempty"`
 
+	// ToolCalls are the tool calls requested by the model.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+
 	// FinishReason indicates why the generation stopped.
 	// Common values: "stop", "length", "content_filter"
 	FinishReason string `json:"finish_reason,omitempty"`
@@ -144,6 +213,10 @@ type StandardResult struct {
 	// Model is the actual model used (may differ from requested).
 	Model string `json:"model,omitempty"`
 
+	// Cached reports whether the result was served from a cache
+	// (see WithCache) instead of the provider.
+	Cached bool `json:"cached,omitempty"`
+
 	// Raw contains the original provider response for debugging.
 	// The type depends on the provider.
 	Raw interface{} `json:"raw,omitempty"`
@@ -154,6 +227,11 @@ type Usage struct {
 	// PromptTokens is the number of tokens in the prompt.
 	PromptTokens int `json:"prompt_tokens"`
 
+	// CachedPromptTokens is the number of prompt tokens read from the
+	// provider's prompt cache, which are billed at a lower rate.
+	// It is included in PromptTokens.
+	CachedPromptTokens int `json:"cached_prompt_tokens,omitempty"`
+
 	// CompletionTokens is the number of tokens in the completion.
 	CompletionTokens int `json:"completion_tokens"`
 
@@ -161,11 +239,25 @@ type Usage struct {
 	TotalTokens int `json:"total_tokens"`
 }
 
+// Add adds the token counts of other to u.
+func (u *Usage) Add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CachedPromptTokens += other.CachedPromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+}
+
 // Validate validates the options and sets default values.
 //
 // Returns an error if required fields are missing or invalid.
 func (o *Options) Validate() error {
-	if o.APIKey == "" {
+	return o.validate(true)
+}
+
+// validate validates the options, requiring an API key if requireAPIKey
+// is set.
+func (o *Options) validate(requireAPIKey bool) error {
+	if requireAPIKey && o.APIKey == "" {
 		return &ValidationError{Field: "APIKey", Message: "API key is required"}
 	}
 
@@ -177,6 +269,14 @@ func (o *Options) Validate() error {
 		return &ValidationError{Field: "Messages", Message: "at least one message is required"}
 	}
 
+	for _, msg := range o.Messages {
+		for _, part := range msg.Parts {
+			if err := part.validate(); err != nil {
+				return &ValidationError{Field: "Messages", Message: err.Error()}
+			}
+		}
+	}
+
 	// Set defaults
 	if o.Temperature == 0 {
 		o.Temperature = 1.0
@@ -194,6 +294,16 @@ func (o *Options) Validate() error {
 		return &ValidationError{Field: "MaxTokens", Message: "must be non-negative"}
 	}
 
+	if o.Schema != nil {
+		if err := o.Schema.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := validateTools(o); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -4,6 +4,10 @@
 //   - Supports all Claude models (Claude 3 Opus, Sonnet, Haiku, etc.)
 //   - System prompts as a dedicated parameter
 //   - Full control over temperature, max tokens, and other parameters
+//   - Streaming responses via llm.ChatStream
+//   - Structured JSON output via a forced tool call
+//   - Tool calling with tool_use and tool_result blocks
+//   - Image and PDF input via message parts
 //
 // Example usage:
 //
@@ -34,6 +38,7 @@ import (
 	"strings"
 
 	"github.com/xifan2333/2sub/pkgs/llm"
+	"github.com/xifan2333/2sub/pkgs/llm/internal/sse"
 )
 
 const (
@@ -44,8 +49,8 @@ const (
 // Provider implements the LLM provider interface for Claude.
 type Provider struct{}
 
-// Ensure Provider implements llm.Provider interface at compile time.
-var _ llm.Provider = (*Provider)(nil)
+// Ensure Provider implements llm.StreamProvider interface at compile time.
+var _ llm.StreamProvider = (*Provider)(nil)
 
 func init() {
 	// Register the provider on package initialization.
@@ -59,13 +64,43 @@ func (p *Provider) Name() string {
 
 // Chat performs LLM chat completion using Claude API.
 func (p *Provider) Chat(ctx context.Context, opts *llm.Options) (*llm.StandardResult, error) {
+	resp, err := p.send(ctx, opts, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.handleNonStream(resp, opts)
+}
+
+// ChatStream performs LLM chat completion using Claude API and streams the
+// response from the messages event stream.
+//
+// Input tokens are reported by message_start and output tokens by the final
+// message_delta event.
+func (p *Provider) ChatStream(ctx context.Context, opts *llm.Options) (*llm.Stream, error) {
+	resp, err := p.send(ctx, opts, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.handleStream(resp, opts), nil
+}
+
+// send sends a messages request and checks the response status.
+func (p *Provider) send(ctx context.Context, opts *llm.Options, stream bool) (*http.Response, error) {
 	baseURL := opts.BaseURL
 	if baseURL == "" {
 		baseURL = defaultBaseURL
 	}
 
 	// Build request
-	reqBody := p.buildRequest(opts)
+	reqBody, err := p.buildRequest(opts)
+	if err != nil {
+		return nil, err
+	}
+	if stream {
+		reqBody["stream"] = true
+	}
 	reqData, err := json.Marshal(reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
@@ -79,28 +114,33 @@ func (p *Provider) Chat(ctx context.Context, opts *llm.Options) (*llm.StandardRe
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("x-api-key", opts.APIKey)
 	req.Header.Set("anthropic-version", defaultAPIVersion)
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
 
 	// Send request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := opts.Client().Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, llm.NewAPIError(p.Name(), resp)
 	}
 
-	return p.handleNonStream(resp)
+	return resp, nil
 }
 
 // buildRequest builds the Claude API request body.
-func (p *Provider) buildRequest(opts *llm.Options) map[string]interface{} {
+func (p *Provider) buildRequest(opts *llm.Options) (map[string]interface{}, error) {
+	messages, err := p.convertMessages(opts.Messages)
+	if err != nil {
+		return nil, err
+	}
+
 	req := map[string]interface{}{
 		"model":    opts.Model,
-		"messages": p.convertMessages(opts.Messages),
+		"messages": messages,
 	}
 
 	// Claude has a dedicated system parameter
@@ -127,16 +167,49 @@ func (p *Provider) buildRequest(opts *llm.Options) map[string]interface{} {
 		req["stop_sequences"] = opts.Stop
 	}
 
+	tools := p.convertTools(opts.Tools)
+
+	switch opts.ToolChoice {
+	case "":
+	case "auto", "none":
+		req["tool_choice"] = map[string]interface{}{"type": opts.ToolChoice}
+	case "required":
+		req["tool_choice"] = map[string]interface{}{"type": "any"}
+	default:
+		req["tool_choice"] = map[string]interface{}{"type": "tool", "name": opts.ToolChoice}
+	}
+
+	// Claude has no JSON mode: structured output is requested by forcing
+	// a tool whose input is the schema
+	if opts.Schema != nil {
+		tool := map[string]interface{}{
+			"name":         opts.Schema.Name,
+			"input_schema": opts.Schema.ObjectSchema(),
+		}
+		if opts.Schema.Description != "" {
+			tool["description"] = opts.Schema.Description
+		}
+		tools = append(tools, tool)
+		req["tool_choice"] = map[string]interface{}{"type": "tool", "name": opts.Schema.Name}
+	}
+
+	if len(tools) > 0 {
+		req["tools"] = tools
+	}
+
 	// Merge extra options
 	for k, v := range opts.Extra {
 		req[k] = v
 	}
 
-	return req
+	return req, nil
 }
 
 // convertMessages converts unified messages to Claude format.
-func (p *Provider) convertMessages(messages []llm.Message) []map[string]interface{} {
+//
+// Tool results are sent as tool_result blocks in a user message; consecutive
+// results are merged into one message.
+func (p *Provider) convertMessages(messages []llm.Message) ([]map[string]interface{}, error) {
 	result := make([]map[string]interface{}, 0, len(messages))
 
 	for _, msg := range messages {
@@ -145,18 +218,121 @@ func (p *Provider) convertMessages(messages []llm.Message) []map[string]interfac
 			continue
 		}
 
+		if msg.Role == "tool" {
+			block := map[string]interface{}{
+				"type":        "tool_result",
+				"tool_use_id": msg.ToolCallID,
+				"content":     msg.Content,
+			}
+			if n := len(result); n > 0 && isToolResults(result[n-1]) {
+				last := result[n-1]
+				last["content"] = append(last["content"].([]map[string]interface{}), block)
+				continue
+			}
+			result = append(result, map[string]interface{}{
+				"role":    "user",
+				"content": []map[string]interface{}{block},
+			})
+			continue
+		}
+
 		m := map[string]interface{}{
 			"role":    msg.Role,
 			"content": msg.Content,
 		}
+		if len(msg.Parts) > 0 || len(msg.ToolCalls) > 0 {
+			blocks, err := p.convertParts(msg.Parts)
+			if err != nil {
+				return nil, err
+			}
+			if len(msg.Parts) == 0 && msg.Content != "" {
+				blocks = append(blocks, map[string]interface{}{"type": "text", "text": msg.Content})
+			}
+			m["content"] = append(blocks, p.convertToolCalls(msg.ToolCalls)...)
+		}
 		result = append(result, m)
 	}
 
+	return result, nil
+}
+
+// convertParts converts message parts to Claude content blocks.
+//
+// Images are sent as image blocks and PDFs as document blocks, from inline
+// base64 data or a URL.
+func (p *Provider) convertParts(parts []llm.Part) ([]map[string]interface{}, error) {
+	blocks := make([]map[string]interface{}, 0, len(parts))
+	for _, part := range parts {
+		if part.Type == llm.PartText {
+			blocks = append(blocks, map[string]interface{}{"type": "text", "text": part.Text})
+			continue
+		}
+
+		var blockType string
+		switch {
+		case part.Media() == "image":
+			blockType = "image"
+		case part.MIMEType == "application/pdf":
+			blockType = "document"
+		default:
+			return nil, llm.UnsupportedPartError(p.Name(), part)
+		}
+
+		source := map[string]interface{}{"type": "url", "url": part.URI}
+		if part.Type == llm.PartData {
+			source = map[string]interface{}{
+				"type":       "base64",
+				"media_type": part.MIMEType,
+				"data":       part.Base64(),
+			}
+		}
+		blocks = append(blocks, map[string]interface{}{"type": blockType, "source": source})
+	}
+	return blocks, nil
+}
+
+// isToolResults reports whether a converted message holds tool results.
+func isToolResults(m map[string]interface{}) bool {
+	blocks, ok := m["content"].([]map[string]interface{})
+	return ok && m["role"] == "user" && len(blocks) > 0 && blocks[0]["type"] == "tool_result"
+}
+
+// convertToolCalls converts tool calls to tool_use content blocks.
+func (p *Provider) convertToolCalls(calls []llm.ToolCall) []map[string]interface{} {
+	blocks := make([]map[string]interface{}, 0, len(calls))
+	for _, call := range calls {
+		input := call.Arguments
+		if len(input) == 0 {
+			input = json.RawMessage("{}")
+		}
+		blocks = append(blocks, map[string]interface{}{
+			"type":  "tool_use",
+			"id":    call.ID,
+			"name":  call.Name,
+			"input": input,
+		})
+	}
+	return blocks
+}
+
+// convertTools converts unified tools to Claude format.
+func (p *Provider) convertTools(tools []llm.Tool) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(tools))
+	for _, tool := range tools {
+		t := map[string]interface{}{
+			"name":         tool.Name,
+			"input_schema": tool.ParametersSchema(),
+		}
+		if tool.Description != "" {
+			t["description"] = tool.Description
+		}
+		result = append(result, t)
+	}
 	return result
 }
 
 // handleNonStream handles non-streaming response.
-func (p *Provider) handleNonStream(resp *http.Response) (*llm.StandardResult, error) {
+func (p *Provider) handleNonStream(resp *http.Response, opts *llm.Options) (*llm.StandardResult, error) {
 	defer resp.Body.Close()
 
 	var apiResp claudeResponse
@@ -168,27 +344,139 @@ func (p *Provider) handleNonStream(resp *http.Response) (*llm.StandardResult, er
 		return nil, fmt.Errorf("no content in response")
 	}
 
-	// Concatenate all text content
+	// Concatenate all text content, or take the input of the forced tool
+	// for structured output
 	var content strings.Builder
+	var toolCalls []llm.ToolCall
 	for _, c := range apiResp.Content {
-		if c.Type == "text" {
+		switch {
+		case opts.Schema != nil && c.Type == "tool_use" && c.Name == opts.Schema.Name:
+			content.Write(c.Input)
+		case opts.Schema == nil && c.Type == "text":
 			content.WriteString(c.Text)
+		case c.Type == "tool_use":
+			toolCalls = append(toolCalls, llm.ToolCall{ID: c.ID, Name: c.Name, Arguments: c.Input})
+		}
+	}
+
+	output := content.String()
+	if opts.Schema != nil {
+		var err error
+		if output, err = opts.Schema.Unwrap(output); err != nil {
+			return nil, err
 		}
 	}
 
 	return &llm.StandardResult{
-		Content:      content.String(),
-		FinishReason: apiResp.StopReason,
+		Content:      output,
+		ToolCalls:    toolCalls,
+		FinishReason: p.finishReason(apiResp.StopReason, opts),
 		Model:        apiResp.Model,
 		Usage: llm.Usage{
-			PromptTokens:     apiResp.Usage.InputTokens,
-			CompletionTokens: apiResp.Usage.OutputTokens,
-			TotalTokens:      apiResp.Usage.InputTokens + apiResp.Usage.OutputTokens,
+			PromptTokens:       apiResp.Usage.promptTokens(),
+			CachedPromptTokens: apiResp.Usage.CacheReadInputTokens,
+			CompletionTokens:   apiResp.Usage.OutputTokens,
+			TotalTokens:        apiResp.Usage.promptTokens() + apiResp.Usage.OutputTokens,
 		},
 		Raw: apiResp,
 	}, nil
 }
 
+// finishReason returns the stop reason to report. The forced tool of
+// structured output stops with "tool_use", which is reported as "end_turn".
+func (p *Provider) finishReason(stopReason string, opts *llm.Options) string {
+	if opts.Schema != nil && stopReason == "tool_use" {
+		return "end_turn"
+	}
+	return stopReason
+}
+
+// handleStream handles streaming response.
+func (p *Provider) handleStream(resp *http.Response, opts *llm.Options) *llm.Stream {
+	reader := sse.NewReader(resp.Body)
+
+	// Tool calls are buffered by content block index until the block stops.
+	// The input of the forced structured output tool is streamed as content.
+	toolCalls := make(map[int]*llm.ToolCall)
+	schemaBlock := -1
+
+	return llm.NewStream(func() (*llm.StreamChunk, error) {
+		for {
+			event, err := reader.Next()
+			if err != nil {
+				return nil, err
+			}
+
+			var apiEvent claudeStreamEvent
+			if err := json.Unmarshal([]byte(event.Data), &apiEvent); err != nil {
+				return nil, fmt.Errorf("failed to decode stream event: %w", err)
+			}
+
+			switch apiEvent.Type {
+			case "message_start":
+				usage := apiEvent.Message.Usage
+				return &llm.StreamChunk{
+					Model: apiEvent.Message.Model,
+					Usage: &llm.Usage{
+						PromptTokens:       usage.promptTokens(),
+						CachedPromptTokens: usage.CacheReadInputTokens,
+						CompletionTokens:   usage.OutputTokens,
+					},
+				}, nil
+
+			case "content_block_start":
+				block := apiEvent.ContentBlock
+				if block.Type != "tool_use" {
+					continue
+				}
+				if opts.Schema != nil && block.Name == opts.Schema.Name {
+					schemaBlock = apiEvent.Index
+					continue
+				}
+				toolCalls[apiEvent.Index] = &llm.ToolCall{ID: block.ID, Name: block.Name}
+
+			case "content_block_delta":
+				switch apiEvent.Delta.Type {
+				case "text_delta":
+					if opts.Schema == nil {
+						return &llm.StreamChunk{Content: apiEvent.Delta.Text}, nil
+					}
+				case "input_json_delta":
+					if apiEvent.Index == schemaBlock {
+						return &llm.StreamChunk{Content: apiEvent.Delta.PartialJSON}, nil
+					}
+					if call, ok := toolCalls[apiEvent.Index]; ok {
+						call.Arguments = append(call.Arguments, apiEvent.Delta.PartialJSON...)
+					}
+				}
+
+			case "content_block_stop":
+				call, ok := toolCalls[apiEvent.Index]
+				if !ok {
+					continue
+				}
+				delete(toolCalls, apiEvent.Index)
+				if len(call.Arguments) == 0 {
+					call.Arguments = json.RawMessage("{}")
+				}
+				return &llm.StreamChunk{ToolCalls: []llm.ToolCall{*call}}, nil
+
+			case "message_delta":
+				return &llm.StreamChunk{
+					FinishReason: p.finishReason(apiEvent.Delta.StopReason, opts),
+					Usage:        &llm.Usage{CompletionTokens: apiEvent.Usage.OutputTokens},
+				}, nil
+
+			case "message_stop":
+				return nil, io.EOF
+
+			case "error":
+				return nil, llm.NewStreamError(p.Name(), apiEvent.Error.Type, apiEvent.Error.Message)
+			}
+		}
+	}, resp.Body)
+}
+
 // Claude API response structures
 type claudeResponse struct {
 	ID         string `json:"id"`
@@ -197,11 +485,54 @@ type claudeResponse struct {
 	Model      string `json:"model"`
 	StopReason string `json:"stop_reason"`
 	Content    []struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
+		Type  string          `json:"type"`
+		Text  string          `json:"text"`
+		ID    string          `json:"id"`
+		Name  string          `json:"name"`
+		Input json.RawMessage `json:"input"`
 	} `json:"content"`
+	Usage claudeUsage `json:"usage"`
+}
+
+// Claude usage structure. Cache reads and writes are not included in
+// InputTokens.
+type claudeUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// promptTokens returns all the input tokens, including cache reads and
+// writes.
+func (u claudeUsage) promptTokens() int {
+	return u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+}
+
+// Claude streaming event structure
+type claudeStreamEvent struct {
+	Type         string `json:"type"`
+	Index        int    `json:"index"`
+	ContentBlock struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"content_block"`
+	Message struct {
+		Model string      `json:"model"`
+		Usage claudeUsage `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
 	Usage struct {
-		InputTokens  int `json:"input_tokens"`
 		OutputTokens int `json:"output_tokens"`
 	} `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
 }
@@ -3,6 +3,10 @@
 // Features:
 //   - Supports Gemini Pro and other Gemini models
 //   - Full control over temperature, max tokens, and other parameters
+//   - Streaming responses via llm.ChatStream
+//   - Structured JSON output via responseSchema
+//   - Tool calling via function declarations
+//   - Audio, image and file input via message parts
 //
 // Example usage:
 //
@@ -27,11 +31,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 
 	"github.com/xifan2333/2sub/pkgs/llm"
+	"github.com/xifan2333/2sub/pkgs/llm/internal/sse"
 )
 
 const defaultBaseURL = "https://generativelanguage.googleapis.com"
@@ -39,8 +43,8 @@ const defaultBaseURL = "https://generativelanguage.googleapis.com"
 // Provider implements the LLM provider interface for Gemini.
 type Provider struct{}
 
-// Ensure Provider implements llm.Provider interface at compile time.
-var _ llm.Provider = (*Provider)(nil)
+// Ensure Provider implements llm.StreamProvider interface at compile time.
+var _ llm.StreamProvider = (*Provider)(nil)
 
 func init() {
 	// Register the provider on package initialization.
@@ -54,6 +58,29 @@ func (p *Provider) Name() string {
 
 // Chat performs LLM chat completion using Gemini API.
 func (p *Provider) Chat(ctx context.Context, opts *llm.Options) (*llm.StandardResult, error) {
+	resp, err := p.send(ctx, opts, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.handleNonStream(resp)
+}
+
+// ChatStream performs LLM chat completion using Gemini API and streams the
+// response from streamGenerateContent as server-sent events.
+//
+// Each event carries the usage so far; the last one holds the totals.
+func (p *Provider) ChatStream(ctx context.Context, opts *llm.Options) (*llm.Stream, error) {
+	resp, err := p.send(ctx, opts, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.handleStream(resp), nil
+}
+
+// send sends a generate content request and checks the response status.
+func (p *Provider) send(ctx context.Context, opts *llm.Options, stream bool) (*http.Response, error) {
 	baseURL := opts.BaseURL
 	if baseURL == "" {
 		baseURL = defaultBaseURL
@@ -61,9 +88,15 @@ func (p *Provider) Chat(ctx context.Context, opts *llm.Options) (*llm.StandardRe
 
 	// Build endpoint
 	endpoint := fmt.Sprintf("%s/v1beta/models/%s:generateContent?key=%s", baseURL, opts.Model, opts.APIKey)
+	if stream {
+		endpoint = fmt.Sprintf("%s/v1beta/models/%s:streamGenerateContent?alt=sse&key=%s", baseURL, opts.Model, opts.APIKey)
+	}
 
 	// Build request
-	reqBody := p.buildRequest(opts)
+	reqBody, err := p.buildRequest(opts)
+	if err != nil {
+		return nil, err
+	}
 	reqData, err := json.Marshal(reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
@@ -77,25 +110,27 @@ func (p *Provider) Chat(ctx context.Context, opts *llm.Options) (*llm.StandardRe
 	req.Header.Set("Content-Type", "application/json")
 
 	// Send request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := opts.Client().Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, llm.NewAPIError(p.Name(), resp)
 	}
 
-	return p.handleNonStream(resp)
+	return resp, nil
 }
 
 // buildRequest builds the Gemini API request body.
-func (p *Provider) buildRequest(opts *llm.Options) map[string]interface{} {
+func (p *Provider) buildRequest(opts *llm.Options) (map[string]interface{}, error) {
+	contents, err := p.convertMessages(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	req := map[string]interface{}{
-		"contents": p.convertMessages(opts),
+		"contents": contents,
 	}
 
 	// Build generation config
@@ -117,10 +152,40 @@ func (p *Provider) buildRequest(opts *llm.Options) map[string]interface{} {
 		genConfig["stopSequences"] = opts.Stop
 	}
 
+	if opts.Schema != nil {
+		genConfig["responseMimeType"] = "application/json"
+		genConfig["responseSchema"] = convertSchema(opts.Schema.Schema)
+	}
+
 	if len(genConfig) > 0 {
 		req["generationConfig"] = genConfig
 	}
 
+	if len(opts.Tools) > 0 {
+		req["tools"] = []map[string]interface{}{
+			{"functionDeclarations": p.convertTools(opts.Tools)},
+		}
+	}
+
+	switch opts.ToolChoice {
+	case "":
+	case "auto", "none":
+		req["toolConfig"] = map[string]interface{}{
+			"functionCallingConfig": map[string]interface{}{"mode": strings.ToUpper(opts.ToolChoice)},
+		}
+	case "required":
+		req["toolConfig"] = map[string]interface{}{
+			"functionCallingConfig": map[string]interface{}{"mode": "ANY"},
+		}
+	default:
+		req["toolConfig"] = map[string]interface{}{
+			"functionCallingConfig": map[string]interface{}{
+				"mode":                 "ANY",
+				"allowedFunctionNames": []string{opts.ToolChoice},
+			},
+		}
+	}
+
 	// System instruction (if supported by the model)
 	if opts.SystemPrompt != "" {
 		req["systemInstruction"] = map[string]interface{}{
@@ -135,35 +200,230 @@ func (p *Provider) buildRequest(opts *llm.Options) map[string]interface{} {
 		req[k] = v
 	}
 
-	return req
+	return req, nil
 }
 
 // convertMessages converts unified messages to Gemini format.
-func (p *Provider) convertMessages(opts *llm.Options) []map[string]interface{} {
+//
+// Tool results are sent as functionResponse parts in a user message;
+// consecutive results are merged into one message.
+func (p *Provider) convertMessages(opts *llm.Options) ([]map[string]interface{}, error) {
 	contents := make([]map[string]interface{}, 0, len(opts.Messages))
 
+	// Gemini identifies function responses by name
+	callNames := make(map[string]string)
+
 	for _, msg := range opts.Messages {
 		// Skip system messages as they're handled separately
 		if msg.Role == "system" {
 			continue
 		}
 
+		if msg.Role == "tool" {
+			name := msg.Name
+			if name == "" {
+				name = callNames[msg.ToolCallID]
+			}
+			part := map[string]interface{}{
+				"functionResponse": map[string]interface{}{
+					"name":     name,
+					"response": toolResponse(msg.Content),
+				},
+			}
+			if n := len(contents); n > 0 && isFunctionResponses(contents[n-1]) {
+				last := contents[n-1]
+				last["parts"] = append(last["parts"].([]map[string]interface{}), part)
+				continue
+			}
+			contents = append(contents, map[string]interface{}{
+				"role":  "user",
+				"parts": []map[string]interface{}{part},
+			})
+			continue
+		}
+
 		// Gemini uses "user" and "model" roles
 		role := msg.Role
 		if role == "assistant" {
 			role = "model"
 		}
 
+		parts := make([]map[string]interface{}, 0, len(msg.Parts)+len(msg.ToolCalls)+1)
+		switch {
+		case len(msg.Parts) > 0:
+			converted, err := p.convertParts(msg.Parts)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, converted...)
+		case msg.Content != "" || len(msg.ToolCalls) == 0:
+			parts = append(parts, map[string]interface{}{"text": msg.Content})
+		}
+		for _, call := range msg.ToolCalls {
+			callNames[call.ID] = call.Name
+			args := call.Arguments
+			if len(args) == 0 {
+				args = json.RawMessage("{}")
+			}
+			parts = append(parts, map[string]interface{}{
+				"functionCall": map[string]interface{}{"name": call.Name, "args": args},
+			})
+		}
+
 		content := map[string]interface{}{
-			"role": role,
-			"parts": []map[string]interface{}{
-				{"text": msg.Content},
-			},
+			"role":  role,
+			"parts": parts,
 		}
 		contents = append(contents, content)
 	}
 
-	return contents
+	return contents, nil
+}
+
+// convertParts converts message parts to Gemini parts: inline data as
+// inlineData and file references (e.g. Files API URIs) as fileData.
+func (p *Provider) convertParts(parts []llm.Part) ([]map[string]interface{}, error) {
+	result := make([]map[string]interface{}, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case llm.PartText:
+			result = append(result, map[string]interface{}{"text": part.Text})
+		case llm.PartData:
+			result = append(result, map[string]interface{}{
+				"inlineData": map[string]interface{}{"mimeType": part.MIMEType, "data": part.Base64()},
+			})
+		case llm.PartFile:
+			fileData := map[string]interface{}{"fileUri": part.URI}
+			if part.MIMEType != "" {
+				fileData["mimeType"] = part.MIMEType
+			}
+			result = append(result, map[string]interface{}{"fileData": fileData})
+		default:
+			return nil, llm.UnsupportedPartError(p.Name(), part)
+		}
+	}
+	return result, nil
+}
+
+// isFunctionResponses reports whether a converted message holds tool results.
+func isFunctionResponses(m map[string]interface{}) bool {
+	parts, ok := m["parts"].([]map[string]interface{})
+	if !ok || m["role"] != "user" || len(parts) == 0 {
+		return false
+	}
+	_, ok = parts[0]["functionResponse"]
+	return ok
+}
+
+// toolResponse converts a tool result to a functionResponse response, which
+// must be an object. Results that are not JSON objects are wrapped.
+func toolResponse(content string) interface{} {
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(content), &object); err == nil && object != nil {
+		return object
+	}
+	return map[string]interface{}{"result": content}
+}
+
+// convertTools converts unified tools to Gemini function declarations.
+func (p *Provider) convertTools(tools []llm.Tool) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(tools))
+	for _, tool := range tools {
+		declaration := map[string]interface{}{"name": tool.Name}
+		if tool.Description != "" {
+			declaration["description"] = tool.Description
+		}
+		if tool.Parameters != nil {
+			declaration["parameters"] = convertSchema(tool.Parameters)
+		}
+		result = append(result, declaration)
+	}
+	return result
+}
+
+// convertParts splits candidate parts into text and tool calls. Calls
+// without an ID are given one from next.
+func convertParts(parts []geminiPart, next *int) (string, []llm.ToolCall) {
+	var content strings.Builder
+	var toolCalls []llm.ToolCall
+	for _, part := range parts {
+		if part.FunctionCall == nil {
+			content.WriteString(part.Text)
+			continue
+		}
+		id := part.FunctionCall.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%d", *next)
+		}
+		*next++
+		args := part.FunctionCall.Args
+		if len(args) == 0 {
+			args = json.RawMessage("{}")
+		}
+		toolCalls = append(toolCalls, llm.ToolCall{ID: id, Name: part.FunctionCall.Name, Arguments: args})
+	}
+	return content.String(), toolCalls
+}
+
+// schemaKeywords are the JSON Schema keywords supported by responseSchema,
+// which is a subset of the OpenAPI schema object.
+var schemaKeywords = map[string]bool{
+	"type":             true,
+	"format":           true,
+	"title":            true,
+	"description":      true,
+	"nullable":         true,
+	"enum":             true,
+	"properties":       true,
+	"required":         true,
+	"items":            true,
+	"minItems":         true,
+	"maxItems":         true,
+	"minLength":        true,
+	"maxLength":        true,
+	"minimum":          true,
+	"maximum":          true,
+	"anyOf":            true,
+	"propertyOrdering": true,
+}
+
+// convertSchema converts a JSON Schema to Gemini's responseSchema format,
+// dropping unsupported keywords such as additionalProperties.
+func convertSchema(schema map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(schema))
+	for k, v := range schema {
+		if !schemaKeywords[k] {
+			continue
+		}
+		switch k {
+		case "properties":
+			if props, ok := v.(map[string]interface{}); ok {
+				converted := make(map[string]interface{}, len(props))
+				for name, prop := range props {
+					if sub, ok := prop.(map[string]interface{}); ok {
+						converted[name] = convertSchema(sub)
+					}
+				}
+				v = converted
+			}
+		case "items":
+			if sub, ok := v.(map[string]interface{}); ok {
+				v = convertSchema(sub)
+			}
+		case "anyOf":
+			if options, ok := v.([]interface{}); ok {
+				converted := make([]interface{}, 0, len(options))
+				for _, option := range options {
+					if sub, ok := option.(map[string]interface{}); ok {
+						converted = append(converted, convertSchema(sub))
+					}
+				}
+				v = converted
+			}
+		}
+		result[k] = v
+	}
+	return result
 }
 
 // handleNonStream handles non-streaming response.
@@ -184,14 +444,13 @@ func (p *Provider) handleNonStream(resp *http.Response) (*llm.StandardResult, er
 		return nil, fmt.Errorf("no parts in candidate content")
 	}
 
-	// Concatenate all text parts
-	var content strings.Builder
-	for _, part := range candidate.Content.Parts {
-		content.WriteString(part.Text)
-	}
+	// Concatenate all text parts and collect tool calls
+	next := 0
+	content, toolCalls := convertParts(candidate.Content.Parts, &next)
 
 	result := &llm.StandardResult{
-		Content:      content.String(),
+		Content:      content,
+		ToolCalls:    toolCalls,
 		FinishReason: candidate.FinishReason,
 		Model:        apiResp.ModelVersion,
 		Raw:          apiResp,
@@ -200,31 +459,83 @@ func (p *Provider) handleNonStream(resp *http.Response) (*llm.StandardResult, er
 	// Add usage information if available
 	if apiResp.UsageMetadata.PromptTokenCount > 0 {
 		result.Usage = llm.Usage{
-			PromptTokens:     apiResp.UsageMetadata.PromptTokenCount,
-			CompletionTokens: apiResp.UsageMetadata.CandidatesTokenCount,
-			TotalTokens:      apiResp.UsageMetadata.TotalTokenCount,
+			PromptTokens:       apiResp.UsageMetadata.PromptTokenCount,
+			CachedPromptTokens: apiResp.UsageMetadata.CachedContentTokenCount,
+			CompletionTokens:   apiResp.UsageMetadata.CandidatesTokenCount,
+			TotalTokens:        apiResp.UsageMetadata.TotalTokenCount,
 		}
 	}
 
 	return result, nil
 }
 
+// handleStream handles streaming response.
+func (p *Provider) handleStream(resp *http.Response) *llm.Stream {
+	reader := sse.NewReader(resp.Body)
+	next := 0
+
+	return llm.NewStream(func() (*llm.StreamChunk, error) {
+		event, err := reader.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var apiResp geminiResponse
+		if err := json.Unmarshal([]byte(event.Data), &apiResp); err != nil {
+			return nil, fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		if apiResp.Error != nil {
+			return nil, llm.NewStreamError(p.Name(), apiResp.Error.Status, apiResp.Error.Message)
+		}
+
+		chunk := &llm.StreamChunk{Model: apiResp.ModelVersion}
+		if len(apiResp.Candidates) > 0 {
+			candidate := apiResp.Candidates[0]
+			chunk.Content, chunk.ToolCalls = convertParts(candidate.Content.Parts, &next)
+			chunk.FinishReason = candidate.FinishReason
+		}
+		if apiResp.UsageMetadata.PromptTokenCount > 0 {
+			chunk.Usage = &llm.Usage{
+				PromptTokens:       apiResp.UsageMetadata.PromptTokenCount,
+				CachedPromptTokens: apiResp.UsageMetadata.CachedContentTokenCount,
+				CompletionTokens:   apiResp.UsageMetadata.CandidatesTokenCount,
+				TotalTokens:        apiResp.UsageMetadata.TotalTokenCount,
+			}
+		}
+		return chunk, nil
+	}, resp.Body)
+}
+
 // Gemini API response structures
 type geminiResponse struct {
 	Candidates []struct {
 		Content struct {
-			Parts []struct {
-				Text string `json:"text"`
-			} `json:"parts"`
-			Role string `json:"role"`
+			Parts []geminiPart `json:"parts"`
+			Role  string       `json:"role"`
 		} `json:"content"`
 		FinishReason string `json:"finishReason"`
 		Index        int    `json:"index"`
 	} `json:"candidates"`
 	UsageMetadata struct {
-		PromptTokenCount     int `json:"promptTokenCount"`
-		CandidatesTokenCount int `json:"candidatesTokenCount"`
-		TotalTokenCount      int `json:"totalTokenCount"`
+		PromptTokenCount        int `json:"promptTokenCount"`
+		CachedContentTokenCount int `json:"cachedContentTokenCount"`
+		CandidatesTokenCount    int `json:"candidatesTokenCount"`
+		TotalTokenCount         int `json:"totalTokenCount"`
 	} `json:"usageMetadata"`
 	ModelVersion string `json:"modelVersion"`
+	Error        *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// Gemini content part structure
+type geminiPart struct {
+	Text         string `json:"text"`
+	FunctionCall *struct {
+		ID   string          `json:"id"`
+		Name string          `json:"name"`
+		Args json.RawMessage `json:"args"`
+	} `json:"functionCall"`
 }
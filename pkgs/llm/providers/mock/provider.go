@@ -0,0 +1,401 @@
+// Package mock provides a scripted LLM provider for deterministic tests.
+//
+// Features:
+//   - Responses matched by a regular expression on the last message, or
+//     served in call order
+//   - Simulated latency, errors, finish reasons, tool calls and usage
+//   - Streaming responses via llm.ChatStream
+//   - Every request is recorded for assertions
+//   - No network access and no API key required
+//
+// The package registers an empty provider as "mock". Tests register their
+// own scripted provider under the same name, replacing it:
+//
+//	import (
+//	    "github.com/xifan2333/2sub/llm"
+//	    "github.com/xifan2333/2sub/llm/providers/mock"
+//	)
+//
+//	provider := mock.New().
+//	    On(`(?i)translate`, mock.Response{Content: "你好"}).
+//	    Then(
+//	        mock.Response{Err: mock.Error(llm.ErrRateLimited, "slow down")},
+//	        mock.Response{Content: "Hello", Latency: 50 * time.Millisecond},
+//	    )
+//	llm.Register(provider)
+//
+//	result, err := llm.Chat(ctx, "mock", opts)
+//	calls := provider.Calls()
+package mock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xifan2333/2sub/pkgs/llm"
+)
+
+// ErrUnscripted means a request matched no rule and the script had no
+// response left for it.
+var ErrUnscripted = errors.New("no scripted response")
+
+// Response is a scripted response.
+type Response struct {
+	// Content is the generated text.
+	Content string
+
+	// ToolCalls are the tool calls requested by the model.
+	ToolCalls []llm.ToolCall
+
+	// FinishReason is the reported finish reason.
+	// Default: "tool_calls" if ToolCalls is set, "stop" otherwise
+	FinishReason string
+
+	// Model is the reported model.
+	// Default: the requested model
+	Model string
+
+	// Usage is the reported usage.
+	// Default: estimated from the request and Content with llm.EstimateTokens
+	Usage *llm.Usage
+
+	// Err is returned instead of a result, after Latency. Use Error to
+	// simulate errors reported by a provider API.
+	Err error
+
+	// Latency is the time to wait before responding. The wait ends early
+	// if the context is done.
+	Latency time.Duration
+}
+
+// rule is a response served to requests whose last message matches a
+// pattern.
+type rule struct {
+	pattern  *regexp.Regexp
+	response Response
+}
+
+// Provider is a scripted LLM provider.
+//
+// Each request is answered by the first rule whose pattern matches the
+// text of its last message, then by the next response of the sequence,
+// then by the default response. Requests that nothing answers fail with
+// ErrUnscripted.
+//
+// A Provider is safe for concurrent use.
+type Provider struct {
+	name string
+
+	mu         sync.Mutex
+	rules      []rule
+	sequence   []Response
+	fallback   *Response
+	calls      []llm.Options
+	chunkRunes int
+}
+
+// Ensure Provider implements the llm.StreamProvider and llm.APIKeyPolicy
+// interfaces at compile time.
+var (
+	_ llm.StreamProvider = (*Provider)(nil)
+	_ llm.APIKeyPolicy   = (*Provider)(nil)
+)
+
+func init() {
+	// Register an empty provider on package initialization.
+	// Tests replace it with their own scripted provider.
+	llm.Register(New())
+}
+
+// New creates a provider named "mock" with an empty script.
+func New() *Provider {
+	return NewNamed("mock")
+}
+
+// NewNamed creates a provider with the given name and an empty script,
+// e.g. to stand in for several backends of an llm.Fallback.
+func NewNamed(name string) *Provider {
+	return &Provider{name: name}
+}
+
+// On adds a rule answering the requests whose last message matches the
+// regular expression pattern with response. Rules are tried in the order
+// they were added and are never used up.
+//
+// Panics if pattern is not a valid regular expression.
+func (p *Provider) On(pattern string, response Response) *Provider {
+	re := regexp.MustCompile(pattern)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rules = append(p.rules, rule{pattern: re, response: response})
+	return p
+}
+
+// Then appends responses to the sequence. Requests that match no rule are
+// answered by the responses of the sequence in order, each used once.
+func (p *Provider) Then(responses ...Response) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sequence = append(p.sequence, responses...)
+	return p
+}
+
+// Default sets the response to requests that match no rule once the
+// sequence is used up.
+func (p *Provider) Default(response Response) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fallback = &response
+	return p
+}
+
+// ChunkSize sets the number of characters of content sent in each chunk
+// by ChatStream. The default, 0, sends one word per chunk.
+func (p *Provider) ChunkSize(runes int) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.chunkRunes = runes
+	return p
+}
+
+// Calls returns copies of the options of every request received, in order.
+func (p *Provider) Calls() []llm.Options {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	calls := make([]llm.Options, len(p.calls))
+	copy(calls, p.calls)
+	return calls
+}
+
+// Reset clears the script and the recorded requests.
+func (p *Provider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.rules = nil
+	p.sequence = nil
+	p.fallback = nil
+	p.calls = nil
+}
+
+// Name returns the provider's unique identifier.
+//
+// Returns "mock" unless created with NewNamed.
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// RequiresAPIKey reports whether a request needs an API key.
+// The mock provider never does.
+func (p *Provider) RequiresAPIKey(opts *llm.Options) bool {
+	return false
+}
+
+// Chat records the request and returns its scripted response.
+func (p *Provider) Chat(ctx context.Context, opts *llm.Options) (*llm.StandardResult, error) {
+	response, err := p.respond(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.result(opts, response), nil
+}
+
+// ChatStream records the request and streams its scripted response.
+//
+// The content is sent in chunks of one word, or of the size set with
+// ChunkSize. The last chunk reports the tool calls, finish reason, model
+// and usage.
+func (p *Provider) ChatStream(ctx context.Context, opts *llm.Options) (*llm.Stream, error) {
+	response, err := p.respond(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	chunkRunes := p.chunkRunes
+	p.mu.Unlock()
+
+	result := p.result(opts, response)
+	chunks := split(result.Content, chunkRunes)
+	final := &llm.StreamChunk{
+		ToolCalls:    result.ToolCalls,
+		FinishReason: result.FinishReason,
+		Model:        result.Model,
+		Usage:        &result.Usage,
+	}
+
+	i := 0
+	recv := func() (*llm.StreamChunk, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		switch {
+		case i < len(chunks):
+			i++
+			return &llm.StreamChunk{Content: chunks[i-1]}, nil
+		case i == len(chunks):
+			i++
+			return final, nil
+		default:
+			return nil, io.EOF
+		}
+	}
+
+	return llm.NewStream(recv, nil), nil
+}
+
+// respond records the request, waits for the latency of its response and
+// returns the response.
+func (p *Provider) respond(ctx context.Context, opts *llm.Options) (Response, error) {
+	response, err := p.next(opts)
+	if err != nil {
+		return Response{}, err
+	}
+
+	if response.Latency > 0 {
+		timer := time.NewTimer(response.Latency)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return Response{}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if response.Err != nil {
+		return Response{}, response.Err
+	}
+	return response, nil
+}
+
+// next records the request and picks its response from the script.
+func (p *Provider) next(opts *llm.Options) (Response, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	call := *opts
+	call.Messages = append([]llm.Message(nil), opts.Messages...)
+	p.calls = append(p.calls, call)
+
+	text := ""
+	if len(opts.Messages) > 0 {
+		text = opts.Messages[len(opts.Messages)-1].Text()
+	}
+	for _, r := range p.rules {
+		if r.pattern.MatchString(text) {
+			return r.response, nil
+		}
+	}
+
+	if len(p.sequence) > 0 {
+		response := p.sequence[0]
+		p.sequence = p.sequence[1:]
+		return response, nil
+	}
+
+	if p.fallback != nil {
+		return *p.fallback, nil
+	}
+
+	return Response{}, fmt.Errorf("%s: call %d: %w", p.name, len(p.calls), ErrUnscripted)
+}
+
+// result converts a response to the standardized format, filling in the
+// defaults.
+func (p *Provider) result(opts *llm.Options, response Response) *llm.StandardResult {
+	result := &llm.StandardResult{
+		Content:      response.Content,
+		ToolCalls:    response.ToolCalls,
+		FinishReason: response.FinishReason,
+		Model:        response.Model,
+		Raw:          response,
+	}
+
+	if result.FinishReason == "" {
+		result.FinishReason = "stop"
+		if len(result.ToolCalls) > 0 {
+			result.FinishReason = "tool_calls"
+		}
+	}
+	if result.Model == "" {
+		result.Model = opts.Model
+	}
+
+	if response.Usage != nil {
+		result.Usage = *response.Usage
+	} else {
+		result.Usage.PromptTokens = llm.EstimateRequestTokens(p.name, opts)
+		result.Usage.CompletionTokens = llm.EstimateTokens(p.name, opts.Model, response.Content)
+		result.Usage.TotalTokens = result.Usage.PromptTokens + result.Usage.CompletionTokens
+	}
+
+	return result
+}
+
+// split splits content into chunks of size runes, or into words if size
+// is 0 or less. Joining the chunks gives back content.
+func split(content string, size int) []string {
+	var chunks []string
+	if size <= 0 {
+		for content != "" {
+			// Each chunk is a word and the spaces that follow it
+			end := strings.IndexFunc(content, isSpace)
+			if end < 0 {
+				return append(chunks, content)
+			}
+			next := strings.IndexFunc(content[end:], func(r rune) bool { return !isSpace(r) })
+			if next < 0 {
+				return append(chunks, content)
+			}
+			chunks = append(chunks, content[:end+next])
+			content = content[end+next:]
+		}
+		return chunks
+	}
+
+	runes := []rune(content)
+	for len(runes) > 0 {
+		n := min(size, len(runes))
+		chunks = append(chunks, string(runes[:n]))
+		runes = runes[n:]
+	}
+	return chunks
+}
+
+// isSpace reports whether r separates words.
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\n' || r == '\t'
+}
+
+// statusCodes are the HTTP status codes reported by Error for each error
+// kind.
+var statusCodes = map[error]int{
+	llm.ErrRateLimited:    http.StatusTooManyRequests,
+	llm.ErrOverloaded:     http.StatusServiceUnavailable,
+	llm.ErrServer:         http.StatusInternalServerError,
+	llm.ErrAuth:           http.StatusUnauthorized,
+	llm.ErrInvalidRequest: http.StatusBadRequest,
+	llm.ErrContextLength:  http.StatusBadRequest,
+}
+
+// Error returns an API error of the given kind, one of the llm.Err* error
+// kinds, as a provider API would report it. errors.Is matches it against
+// kind, and llm.IsRetryable classifies it like a real error.
+func Error(kind error, message string) *llm.APIError {
+	return &llm.APIError{
+		Provider:   "mock",
+		StatusCode: statusCodes[kind],
+		Response:   message,
+		Kind:       kind,
+	}
+}
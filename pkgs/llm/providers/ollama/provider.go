@@ -0,0 +1,431 @@
+// Package ollama provides an LLM provider implementation for local models
+// served by Ollama.
+//
+// Features:
+//   - Uses the native /api/chat endpoint
+//   - No API key required (an API key is sent as a bearer token if set,
+//     for servers behind an authenticating proxy)
+//   - Model discovery via llm.ListModels (/api/tags)
+//   - Streaming responses via llm.ChatStream
+//   - Structured JSON output via the format parameter
+//   - Tool calling and image input
+//
+// Example usage:
+//
+//	import (
+//	    "context"
+//	    "github.com/xifan2333/2sub/llm"
+//	    _ "github.com/xifan2333/2sub/llm/providers/ollama"
+//	)
+//
+//	opts := &llm.Options{
+//	    Model: "qwen2.5:14b",
+//	    Messages: []llm.Message{
+//	        {Role: "user", Content: "Hello!"},
+//	    },
+//	}
+//	result, err := llm.Chat(ctx, "ollama", opts)
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/xifan2333/2sub/pkgs/llm"
+)
+
+const defaultBaseURL = "http://localhost:11434"
+
+// maxLineSize is the maximum size of a single line in a streamed response.
+const maxLineSize = 4 * 1024 * 1024
+
+// Provider implements the LLM provider interface for Ollama.
+type Provider struct{}
+
+// Ensure Provider implements the llm.StreamProvider, llm.ModelLister and
+// llm.APIKeyPolicy interfaces at compile time.
+var (
+	_ llm.StreamProvider = (*Provider)(nil)
+	_ llm.ModelLister    = (*Provider)(nil)
+	_ llm.APIKeyPolicy   = (*Provider)(nil)
+)
+
+func init() {
+	// Register the provider on package initialization.
+	llm.Register(&Provider{})
+}
+
+// Name returns the provider's unique identifier.
+func (p *Provider) Name() string {
+	return "ollama"
+}
+
+// RequiresAPIKey reports whether a request needs an API key.
+// Local servers never do.
+func (p *Provider) RequiresAPIKey(opts *llm.Options) bool {
+	return false
+}
+
+// Chat performs LLM chat completion using the Ollama API.
+func (p *Provider) Chat(ctx context.Context, opts *llm.Options) (*llm.StandardResult, error) {
+	resp, err := p.send(ctx, opts, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.handleNonStream(resp)
+}
+
+// ChatStream performs LLM chat completion using the Ollama API and streams
+// the response, which is sent as newline-delimited JSON.
+//
+// Usage is reported by the last message, which has done set.
+func (p *Provider) ChatStream(ctx context.Context, opts *llm.Options) (*llm.Stream, error) {
+	resp, err := p.send(ctx, opts, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.handleStream(resp), nil
+}
+
+// ListModels lists the models pulled on the server (/api/tags).
+func (p *Provider) ListModels(ctx context.Context, opts *llm.Options) ([]llm.ModelInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL(opts)+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if opts.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+opts.APIKey)
+	}
+
+	resp, err := opts.Client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, llm.NewAPIError(p.Name(), resp)
+	}
+	defer resp.Body.Close()
+
+	var apiResp ollamaTags
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	models := make([]llm.ModelInfo, 0, len(apiResp.Models))
+	for _, m := range apiResp.Models {
+		models = append(models, llm.ModelInfo{
+			ID:         m.Name,
+			Size:       m.Size,
+			ModifiedAt: m.ModifiedAt,
+		})
+	}
+	return models, nil
+}
+
+// baseURL returns the server URL of opts.
+func baseURL(opts *llm.Options) string {
+	if opts.BaseURL == "" {
+		return defaultBaseURL
+	}
+	return strings.TrimSuffix(opts.BaseURL, "/")
+}
+
+// send sends a chat request and checks the response status.
+func (p *Provider) send(ctx context.Context, opts *llm.Options, stream bool) (*http.Response, error) {
+	// Build request
+	reqBody, err := p.buildRequest(opts)
+	if err != nil {
+		return nil, err
+	}
+	reqBody["stream"] = stream
+	reqData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL(opts)+"/api/chat", bytes.NewReader(reqData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if opts.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+opts.APIKey)
+	}
+
+	// Send request
+	resp, err := opts.Client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, llm.NewAPIError(p.Name(), resp)
+	}
+
+	return resp, nil
+}
+
+// buildRequest builds the Ollama API request body.
+func (p *Provider) buildRequest(opts *llm.Options) (map[string]interface{}, error) {
+	messages, err := p.convertMessages(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	req := map[string]interface{}{
+		"model":    opts.Model,
+		"messages": messages,
+	}
+
+	// Sampling parameters go in the model options
+	modelOptions := make(map[string]interface{})
+
+	if opts.Temperature > 0 {
+		modelOptions["temperature"] = opts.Temperature
+	}
+
+	if opts.MaxTokens > 0 {
+		modelOptions["num_predict"] = opts.MaxTokens
+	}
+
+	if opts.TopP > 0 {
+		modelOptions["top_p"] = opts.TopP
+	}
+
+	if len(opts.Stop) > 0 {
+		modelOptions["stop"] = opts.Stop
+	}
+
+	if len(modelOptions) > 0 {
+		req["options"] = modelOptions
+	}
+
+	// Ollama accepts a JSON schema as the output format
+	if opts.Schema != nil {
+		req["format"] = opts.Schema.Schema
+	}
+
+	// Ollama has no tool choice: "none" sends no tools
+	if len(opts.Tools) > 0 && opts.ToolChoice != "none" {
+		req["tools"] = p.convertTools(opts.Tools)
+	}
+
+	// Merge extra options
+	for k, v := range opts.Extra {
+		req[k] = v
+	}
+
+	return req, nil
+}
+
+// convertMessages converts unified messages to Ollama format.
+//
+// Images are sent base64-encoded in the images field of their message.
+func (p *Provider) convertMessages(opts *llm.Options) ([]map[string]interface{}, error) {
+	messages := make([]map[string]interface{}, 0, len(opts.Messages)+1)
+
+	// Add system prompt if present
+	if opts.SystemPrompt != "" {
+		messages = append(messages, map[string]interface{}{
+			"role":    "system",
+			"content": opts.SystemPrompt,
+		})
+	}
+
+	// Add conversation messages
+	for _, msg := range opts.Messages {
+		m := map[string]interface{}{
+			"role":    msg.Role,
+			"content": msg.Content,
+		}
+
+		if len(msg.Parts) > 0 {
+			var content strings.Builder
+			var images []string
+			for _, part := range msg.Parts {
+				switch {
+				case part.Type == llm.PartText:
+					content.WriteString(part.Text)
+				case part.Type == llm.PartData && part.Media() == "image":
+					images = append(images, part.Base64())
+				default:
+					return nil, llm.UnsupportedPartError(p.Name(), part)
+				}
+			}
+			m["content"] = content.String()
+			if len(images) > 0 {
+				m["images"] = images
+			}
+		}
+
+		if msg.Role == "tool" && msg.Name != "" {
+			m["tool_name"] = msg.Name
+		}
+
+		if len(msg.ToolCalls) > 0 {
+			calls := make([]map[string]interface{}, 0, len(msg.ToolCalls))
+			for _, call := range msg.ToolCalls {
+				args := call.Arguments
+				if len(args) == 0 {
+					args = json.RawMessage("{}")
+				}
+				calls = append(calls, map[string]interface{}{
+					"function": map[string]interface{}{"name": call.Name, "arguments": args},
+				})
+			}
+			m["tool_calls"] = calls
+		}
+
+		messages = append(messages, m)
+	}
+
+	return messages, nil
+}
+
+// convertTools converts unified tools to Ollama function tools.
+func (p *Provider) convertTools(tools []llm.Tool) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(tools))
+	for _, tool := range tools {
+		result = append(result, map[string]interface{}{
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":        tool.Name,
+				"description": tool.Description,
+				"parameters":  tool.ParametersSchema(),
+			},
+		})
+	}
+	return result
+}
+
+// handleNonStream handles non-streaming response.
+func (p *Provider) handleNonStream(resp *http.Response) (*llm.StandardResult, error) {
+	defer resp.Body.Close()
+
+	var apiResp ollamaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if apiResp.Error != "" {
+		return nil, llm.NewStreamError(p.Name(), "", apiResp.Error)
+	}
+
+	next := 0
+	return &llm.StandardResult{
+		Content:      apiResp.Message.Content,
+		ToolCalls:    convertToolCalls(apiResp.Message.ToolCalls, &next),
+		FinishReason: apiResp.DoneReason,
+		Model:        apiResp.Model,
+		Usage: llm.Usage{
+			PromptTokens:     apiResp.PromptEvalCount,
+			CompletionTokens: apiResp.EvalCount,
+			TotalTokens:      apiResp.PromptEvalCount + apiResp.EvalCount,
+		},
+		Raw: apiResp,
+	}, nil
+}
+
+// handleStream handles streaming response.
+func (p *Provider) handleStream(resp *http.Response) *llm.Stream {
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	next := 0
+
+	return llm.NewStream(func() (*llm.StreamChunk, error) {
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+
+			var apiResp ollamaResponse
+			if err := json.Unmarshal(line, &apiResp); err != nil {
+				return nil, fmt.Errorf("failed to decode stream chunk: %w", err)
+			}
+			if apiResp.Error != "" {
+				return nil, llm.NewStreamError(p.Name(), "", apiResp.Error)
+			}
+
+			chunk := &llm.StreamChunk{
+				Content:   apiResp.Message.Content,
+				ToolCalls: convertToolCalls(apiResp.Message.ToolCalls, &next),
+				Model:     apiResp.Model,
+			}
+			if apiResp.Done {
+				chunk.FinishReason = apiResp.DoneReason
+				chunk.Usage = &llm.Usage{
+					PromptTokens:     apiResp.PromptEvalCount,
+					CompletionTokens: apiResp.EvalCount,
+				}
+			}
+			return chunk, nil
+		}
+
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}, resp.Body)
+}
+
+// convertToolCalls converts Ollama tool calls, which have no IDs, giving
+// them IDs from next.
+func convertToolCalls(calls []ollamaToolCall, next *int) []llm.ToolCall {
+	var result []llm.ToolCall
+	for _, call := range calls {
+		args := call.Function.Arguments
+		if len(args) == 0 {
+			args = json.RawMessage("{}")
+		}
+		result = append(result, llm.ToolCall{
+			ID:        fmt.Sprintf("call_%d", *next),
+			Name:      call.Function.Name,
+			Arguments: args,
+		})
+		*next++
+	}
+	return result
+}
+
+// Ollama API response structures
+type ollamaResponse struct {
+	Model     string    `json:"model"`
+	CreatedAt time.Time `json:"created_at"`
+	Message   struct {
+		Role      string           `json:"role"`
+		Content   string           `json:"content"`
+		ToolCalls []ollamaToolCall `json:"tool_calls"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	DoneReason      string `json:"done_reason"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	Error           string `json:"error"`
+}
+
+// Ollama tool call structure
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+// Ollama model list structure
+type ollamaTags struct {
+	Models []struct {
+		Name       string    `json:"name"`
+		Size       int64     `json:"size"`
+		ModifiedAt time.Time `json:"modified_at"`
+	} `json:"models"`
+}
@@ -4,6 +4,11 @@
 //   - Supports all OpenAI chat models (GPT-3.5, GPT-4, etc.)
 //   - Compatible with OpenAI-compatible APIs (via BaseURL)
 //   - Full control over temperature, max tokens, and other parameters
+//   - Streaming responses via llm.ChatStream
+//   - Structured JSON output via response_format json_schema
+//   - Tool calling via function tools
+//   - Image, audio and file input via message parts
+//   - Model discovery via llm.ListModels; no API key needed with a custom BaseURL
 //
 // Example usage:
 //
@@ -30,8 +35,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/xifan2333/2sub/pkgs/llm"
+	"github.com/xifan2333/2sub/pkgs/llm/internal/sse"
 )
 
 const defaultBaseURL = "https://api.openai.com/v1"
@@ -39,8 +46,13 @@ const defaultBaseURL = "https://api.openai.com/v1"
 // Provider implements the LLM provider interface for OpenAI.
 type Provider struct{}
 
-// Ensure Provider implements llm.Provider interface at compile time.
-var _ llm.Provider = (*Provider)(nil)
+// Ensure Provider implements the llm.StreamProvider, llm.ModelLister and
+// llm.APIKeyPolicy interfaces at compile time.
+var (
+	_ llm.StreamProvider = (*Provider)(nil)
+	_ llm.ModelLister    = (*Provider)(nil)
+	_ llm.APIKeyPolicy   = (*Provider)(nil)
+)
 
 func init() {
 	// Register the provider on package initialization.
@@ -54,13 +66,90 @@ func (p *Provider) Name() string {
 
 // Chat performs LLM chat completion using OpenAI API.
 func (p *Provider) Chat(ctx context.Context, opts *llm.Options) (*llm.StandardResult, error) {
+	resp, err := p.send(ctx, opts, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.handleNonStream(resp, opts)
+}
+
+// ChatStream performs LLM chat completion using OpenAI API and streams the
+// response as server-sent events.
+//
+// Usage is requested with stream_options.include_usage and reported by the
+// last chunk.
+func (p *Provider) ChatStream(ctx context.Context, opts *llm.Options) (*llm.Stream, error) {
+	resp, err := p.send(ctx, opts, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.handleStream(resp), nil
+}
+
+// RequiresAPIKey reports whether a request needs an API key: only the
+// official API does, compatible servers set with BaseURL may not.
+func (p *Provider) RequiresAPIKey(opts *llm.Options) bool {
+	return opts.BaseURL == ""
+}
+
+// ListModels lists the models available from the /models endpoint.
+func (p *Provider) ListModels(ctx context.Context, opts *llm.Options) ([]llm.ModelInfo, error) {
+	baseURL := opts.BaseURL
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if opts.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+opts.APIKey)
+	}
+
+	resp, err := opts.Client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, llm.NewAPIError(p.Name(), resp)
+	}
+	defer resp.Body.Close()
+
+	var apiResp openAIModelList
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	models := make([]llm.ModelInfo, 0, len(apiResp.Data))
+	for _, m := range apiResp.Data {
+		info := llm.ModelInfo{ID: m.ID, OwnedBy: m.OwnedBy}
+		if m.Created > 0 {
+			info.ModifiedAt = time.Unix(m.Created, 0)
+		}
+		models = append(models, info)
+	}
+	return models, nil
+}
+
+// send sends a chat completion request and checks the response status.
+func (p *Provider) send(ctx context.Context, opts *llm.Options, stream bool) (*http.Response, error) {
 	baseURL := opts.BaseURL
 	if baseURL == "" {
 		baseURL = defaultBaseURL
 	}
 
 	// Build request
-	reqBody := p.buildRequest(opts)
+	reqBody, err := p.buildRequest(opts)
+	if err != nil {
+		return nil, err
+	}
+	if stream {
+		reqBody["stream"] = true
+		reqBody["stream_options"] = map[string]interface{}{"include_usage": true}
+	}
 	reqData, err := json.Marshal(reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
@@ -72,29 +161,36 @@ func (p *Provider) Chat(ctx context.Context, opts *llm.Options) (*llm.StandardRe
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+opts.APIKey)
+	if opts.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+opts.APIKey)
+	}
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
 
 	// Send request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := opts.Client().Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, llm.NewAPIError(p.Name(), resp)
 	}
 
-	return p.handleNonStream(resp)
+	return resp, nil
 }
 
 // buildRequest builds the OpenAI API request body.
-func (p *Provider) buildRequest(opts *llm.Options) map[string]interface{} {
+func (p *Provider) buildRequest(opts *llm.Options) (map[string]interface{}, error) {
+	messages, err := p.convertMessages(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	req := map[string]interface{}{
 		"model":    opts.Model,
-		"messages": p.convertMessages(opts),
+		"messages": messages,
 	}
 
 	if opts.Temperature > 0 {
@@ -113,16 +209,47 @@ func (p *Provider) buildRequest(opts *llm.Options) map[string]interface{} {
 		req["stop"] = opts.Stop
 	}
 
+	if len(opts.Tools) > 0 {
+		req["tools"] = p.convertTools(opts.Tools)
+	}
+
+	switch opts.ToolChoice {
+	case "":
+	case "auto", "none", "required":
+		req["tool_choice"] = opts.ToolChoice
+	default:
+		req["tool_choice"] = map[string]interface{}{
+			"type":     "function",
+			"function": map[string]interface{}{"name": opts.ToolChoice},
+		}
+	}
+
+	// Structured output requires an object root
+	if opts.Schema != nil {
+		jsonSchema := map[string]interface{}{
+			"name":   opts.Schema.Name,
+			"schema": opts.Schema.ObjectSchema(),
+			"strict": opts.Schema.Strict,
+		}
+		if opts.Schema.Description != "" {
+			jsonSchema["description"] = opts.Schema.Description
+		}
+		req["response_format"] = map[string]interface{}{
+			"type":        "json_schema",
+			"json_schema": jsonSchema,
+		}
+	}
+
 	// Merge extra options
 	for k, v := range opts.Extra {
 		req[k] = v
 	}
 
-	return req
+	return req, nil
 }
 
 // convertMessages converts unified messages to OpenAI format.
-func (p *Provider) convertMessages(opts *llm.Options) []map[string]interface{} {
+func (p *Provider) convertMessages(opts *llm.Options) ([]map[string]interface{}, error) {
 	messages := make([]map[string]interface{}, 0, len(opts.Messages)+1)
 
 	// Add system prompt if present
@@ -139,17 +266,133 @@ func (p *Provider) convertMessages(opts *llm.Options) []map[string]interface{} {
 			"role":    msg.Role,
 			"content": msg.Content,
 		}
-		if msg.Name != "" {
+		if len(msg.Parts) > 0 {
+			content, err := p.convertParts(msg.Parts)
+			if err != nil {
+				return nil, err
+			}
+			m["content"] = content
+		}
+		switch {
+		case msg.Role == "tool":
+			m["tool_call_id"] = msg.ToolCallID
+		case msg.Name != "":
 			m["name"] = msg.Name
 		}
+		if len(msg.ToolCalls) > 0 {
+			if msg.Content == "" && len(msg.Parts) == 0 {
+				m["content"] = nil
+			}
+			m["tool_calls"] = p.convertToolCalls(msg.ToolCalls)
+		}
 		messages = append(messages, m)
 	}
 
-	return messages
+	return messages, nil
+}
+
+// audioFormats maps the audio MIME types accepted as input_audio to their
+// format names.
+var audioFormats = map[string]string{
+	"audio/wav":   "wav",
+	"audio/x-wav": "wav",
+	"audio/wave":  "wav",
+	"audio/mpeg":  "mp3",
+	"audio/mp3":   "mp3",
+}
+
+// convertParts converts message parts to OpenAI content parts.
+//
+// Images are sent as image_url (inline data as a data: URL), wav and mp3
+// audio as input_audio, and other files as file parts.
+func (p *Provider) convertParts(parts []llm.Part) ([]map[string]interface{}, error) {
+	result := make([]map[string]interface{}, 0, len(parts))
+	for _, part := range parts {
+		switch {
+		case part.Type == llm.PartText:
+			result = append(result, map[string]interface{}{"type": "text", "text": part.Text})
+
+		case part.Media() == "image":
+			url := part.URI
+			if part.Type == llm.PartData {
+				url = part.DataURL()
+			}
+			result = append(result, map[string]interface{}{
+				"type":      "image_url",
+				"image_url": map[string]interface{}{"url": url},
+			})
+
+		case part.Type == llm.PartData && part.Media() == "audio":
+			format, ok := audioFormats[part.MIMEType]
+			if !ok {
+				return nil, llm.UnsupportedPartError(p.Name(), part)
+			}
+			result = append(result, map[string]interface{}{
+				"type":        "input_audio",
+				"input_audio": map[string]interface{}{"data": part.Base64(), "format": format},
+			})
+
+		case part.Type == llm.PartData:
+			result = append(result, map[string]interface{}{
+				"type": "file",
+				"file": map[string]interface{}{"file_data": part.DataURL()},
+			})
+
+		case part.Type == llm.PartFile && part.Media() != "audio":
+			result = append(result, map[string]interface{}{
+				"type": "file",
+				"file": map[string]interface{}{"file_id": part.URI},
+			})
+
+		default:
+			return nil, llm.UnsupportedPartError(p.Name(), part)
+		}
+	}
+	return result, nil
+}
+
+// convertTools converts unified tools to OpenAI function tools.
+func (p *Provider) convertTools(tools []llm.Tool) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(tools))
+	for _, tool := range tools {
+		function := map[string]interface{}{
+			"name":       tool.Name,
+			"parameters": tool.ParametersSchema(),
+		}
+		if tool.Description != "" {
+			function["description"] = tool.Description
+		}
+		result = append(result, map[string]interface{}{
+			"type":     "function",
+			"function": function,
+		})
+	}
+	return result
+}
+
+// convertToolCalls converts unified tool calls to OpenAI format, where the
+// arguments are a JSON string.
+func (p *Provider) convertToolCalls(calls []llm.ToolCall) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(calls))
+	for _, call := range calls {
+		arguments := string(call.Arguments)
+		if arguments == "" {
+			arguments = "{}"
+		}
+		result = append(result, map[string]interface{}{
+			"id":   call.ID,
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":      call.Name,
+				"arguments": arguments,
+			},
+		})
+	}
+	return result
 }
 
 // handleNonStream handles non-streaming response.
-func (p *Provider) handleNonStream(resp *http.Response) (*llm.StandardResult, error) {
+func (p *Provider) handleNonStream(resp *http.Response, opts *llm.Options) (*llm.StandardResult, error) {
 	defer resp.Body.Close()
 
 	var apiResp openAIResponse
@@ -162,19 +405,99 @@ func (p *Provider) handleNonStream(resp *http.Response) (*llm.StandardResult, er
 	}
 
 	choice := apiResp.Choices[0]
+	content := choice.Message.Content
+	if opts.Schema != nil {
+		var err error
+		if content, err = opts.Schema.Unwrap(content); err != nil {
+			return nil, err
+		}
+	}
+
+	var toolCalls []llm.ToolCall
+	for _, call := range choice.Message.ToolCalls {
+		toolCalls = append(toolCalls, llm.ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: json.RawMessage(call.Function.Arguments),
+		})
+	}
+
 	return &llm.StandardResult{
-		Content:      choice.Message.Content,
+		Content:      content,
+		ToolCalls:    toolCalls,
 		FinishReason: choice.FinishReason,
 		Model:        apiResp.Model,
 		Usage: llm.Usage{
-			PromptTokens:     apiResp.Usage.PromptTokens,
-			CompletionTokens: apiResp.Usage.CompletionTokens,
-			TotalTokens:      apiResp.Usage.TotalTokens,
+			PromptTokens:       apiResp.Usage.PromptTokens,
+			CachedPromptTokens: apiResp.Usage.PromptTokensDetails.CachedTokens,
+			CompletionTokens:   apiResp.Usage.CompletionTokens,
+			TotalTokens:        apiResp.Usage.TotalTokens,
 		},
 		Raw: apiResp,
 	}, nil
 }
 
+// handleStream handles streaming response.
+func (p *Provider) handleStream(resp *http.Response) *llm.Stream {
+	reader := sse.NewReader(resp.Body)
+
+	// Tool calls arrive in fragments keyed by index and are emitted with
+	// the finish reason
+	var toolCalls []llm.ToolCall
+
+	return llm.NewStream(func() (*llm.StreamChunk, error) {
+		event, err := reader.Next()
+		if err != nil {
+			return nil, err
+		}
+		if event.Data == "[DONE]" {
+			return nil, io.EOF
+		}
+
+		var apiChunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(event.Data), &apiChunk); err != nil {
+			return nil, fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		if apiChunk.Error != nil {
+			return nil, llm.NewStreamError(p.Name(), apiChunk.Error.Type, apiChunk.Error.Message)
+		}
+
+		chunk := &llm.StreamChunk{Model: apiChunk.Model}
+		if len(apiChunk.Choices) > 0 {
+			choice := apiChunk.Choices[0]
+			chunk.Content = choice.Delta.Content
+			chunk.FinishReason = choice.FinishReason
+
+			for _, call := range choice.Delta.ToolCalls {
+				for len(toolCalls) <= call.Index {
+					toolCalls = append(toolCalls, llm.ToolCall{})
+				}
+				if call.ID != "" {
+					toolCalls[call.Index].ID = call.ID
+				}
+				if call.Function.Name != "" {
+					toolCalls[call.Index].Name = call.Function.Name
+				}
+				toolCalls[call.Index].Arguments = append(toolCalls[call.Index].Arguments, call.Function.Arguments...)
+			}
+
+			if choice.FinishReason != "" && len(toolCalls) > 0 {
+				chunk.ToolCalls = toolCalls
+				toolCalls = nil
+			}
+		}
+		if apiChunk.Usage != nil {
+			chunk.Usage = &llm.Usage{
+				PromptTokens:       apiChunk.Usage.PromptTokens,
+				CachedPromptTokens: apiChunk.Usage.PromptTokensDetails.CachedTokens,
+				CompletionTokens:   apiChunk.Usage.CompletionTokens,
+				TotalTokens:        apiChunk.Usage.TotalTokens,
+			}
+		}
+		return chunk, nil
+	}, resp.Body)
+}
+
 // OpenAI API response structures
 type openAIResponse struct {
 	ID      string `json:"id"`
@@ -185,13 +508,60 @@ type openAIResponse struct {
 		Index        int    `json:"index"`
 		FinishReason string `json:"finish_reason"`
 		Message      struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
+			Role      string           `json:"role"`
+			Content   string           `json:"content"`
+			ToolCalls []openAIToolCall `json:"tool_calls"`
 		} `json:"message"`
 	} `json:"choices"`
-	Usage struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage"`
+	Usage openAIUsage `json:"usage"`
+}
+
+// OpenAI usage structure
+type openAIUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
+
+// OpenAI model list structure
+type openAIModelList struct {
+	Data []struct {
+		ID      string `json:"id"`
+		Created int64  `json:"created"`
+		OwnedBy string `json:"owned_by"`
+	} `json:"data"`
+}
+
+// OpenAI tool call structure, also used for streamed fragments
+type openAIToolCall struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// OpenAI streaming chunk structure
+type openAIStreamChunk struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Index        int    `json:"index"`
+		FinishReason string `json:"finish_reason"`
+		Delta        struct {
+			Role      string           `json:"role"`
+			Content   string           `json:"content"`
+			ToolCalls []openAIToolCall `json:"tool_calls"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
 }
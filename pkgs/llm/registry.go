@@ -11,8 +11,10 @@ import (
 // The registry is thread-safe and can be accessed concurrently.
 // Providers are typically registered during package initialization using init() functions.
 type Registry struct {
-	mu        sync.RWMutex
-	providers map[string]Provider
+	mu               sync.RWMutex
+	providers        map[string]Provider
+	middlewares      []Middleware
+	entryMiddlewares map[string][]Middleware
 }
 
 // globalRegistry is the default registry used by package-level functions.
@@ -72,19 +74,23 @@ func (r *Registry) Register(provider Provider) {
 	r.providers[provider.Name()] = provider
 }
 
-// Get retrieves a provider by name from this registry.
+// Get retrieves a provider by name from this registry, wrapped with the
+// middlewares installed with Use and UseFor.
 //
 // Returns an error if the provider is not found.
 // This method is safe for concurrent use.
 func (r *Registry) Get(name string) (Provider, error) {
 	r.mu.RLock()
-	defer r.mu.RUnlock()
-
 	provider, ok := r.providers[name]
+	middlewares := make([]Middleware, 0, len(r.middlewares)+len(r.entryMiddlewares[name]))
+	middlewares = append(middlewares, r.middlewares...)
+	middlewares = append(middlewares, r.entryMiddlewares[name]...)
+	r.mu.RUnlock()
+
 	if !ok {
 		return nil, fmt.Errorf("provider '%s' not found", name)
 	}
-	return provider, nil
+	return Chain(provider, middlewares...), nil
 }
 
 // List returns all registered provider names from this registry.
@@ -111,7 +117,9 @@ func (r *Registry) List() []string {
 //   - providerName: Name of the provider to use (e.g., "openai", "claude", "gemini")
 //   - opts: Provider options including API key, model, messages, etc.
 //
-// Returns the standardized chat result or an error.
+// Returns the standardized chat result or an error. If opts.Schema is set
+// and the output does not conform to it, the result is returned together
+// with an error wrapping ErrSchemaMismatch.
 //
 // Example:
 //
@@ -136,7 +144,7 @@ func Chat(ctx context.Context, providerName string, opts *Options) (*StandardRes
 		return nil, err
 	}
 
-	if err := opts.Validate(); err != nil {
+	if err := validateFor(provider, opts); err != nil {
 		return nil, err
 	}
 
@@ -144,6 +152,23 @@ func Chat(ctx context.Context, providerName string, opts *Options) (*StandardRes
 	if err != nil {
 		return nil, fmt.Errorf("chat failed: %w", err)
 	}
+	recordUsage(ctx, provider.Name(), opts, result)
+
+	if err := checkOutput(opts, result); err != nil {
+		return result, err
+	}
 
 	return result, nil
 }
+
+// validateFor validates the options of a call to provider, or to the
+// provider it wraps.
+func validateFor(provider Provider, opts *Options) error {
+	if v, ok := capability[OptionsValidator](provider); ok {
+		return v.ValidateOptions(opts)
+	}
+	if policy, ok := capability[APIKeyPolicy](provider); ok {
+		return opts.validate(policy.RequiresAPIKey(opts))
+	}
+	return opts.Validate()
+}
@@ -0,0 +1,236 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy controls how failed LLM calls are retried.
+//
+// Delays grow exponentially from InitialBackoff by Multiplier up to
+// MaxBackoff, with random jitter. A Retry-After delay sent by the provider
+// takes precedence over the computed backoff. Retries never wait past the
+// caller's context deadline: if the next attempt cannot start before the
+// deadline, the last error is returned immediately.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first.
+	// Default: 4
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry.
+	// Default: 1s
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the computed delay between attempts.
+	// A longer Retry-After delay requested by the provider is still honored.
+	// Default: 30s
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor applied to the delay after each retry.
+	// Default: 2
+	Multiplier float64
+
+	// Jitter is the fraction of the delay that is randomized (0 to 1).
+	// A jitter of 0.2 waits between 80% and 120% of the delay.
+	// Default: 0.2
+	Jitter float64
+
+	// DisableJitter waits exactly the delay, ignoring Jitter.
+	DisableJitter bool
+
+	// Retryable decides whether an error is retried.
+	// Default: IsRetryable
+	Retryable func(error) bool
+
+	// OnRetry is called before waiting for each retry, with the attempt
+	// that failed (starting at 1), its error and the delay. Optional.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// Validate validates the policy and sets default values.
+func (p *RetryPolicy) Validate() error {
+	if p.MaxAttempts < 0 {
+		return &ValidationError{Field: "MaxAttempts", Message: "must be non-negative"}
+	}
+	if p.InitialBackoff < 0 {
+		return &ValidationError{Field: "InitialBackoff", Message: "must be non-negative"}
+	}
+	if p.MaxBackoff < 0 {
+		return &ValidationError{Field: "MaxBackoff", Message: "must be non-negative"}
+	}
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		return &ValidationError{Field: "Multiplier", Message: "must be at least 1"}
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return &ValidationError{Field: "Jitter", Message: "must be between 0 and 1"}
+	}
+
+	// Set defaults
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = 4
+	}
+	if p.InitialBackoff == 0 {
+		p.InitialBackoff = time.Second
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = 30 * time.Second
+	}
+	if p.Multiplier == 0 {
+		p.Multiplier = 2
+	}
+	if p.Jitter == 0 {
+		p.Jitter = 0.2
+	}
+	if p.Retryable == nil {
+		p.Retryable = IsRetryable
+	}
+
+	return nil
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, or the
+// attempts are exhausted.
+//
+// Returns the error of the last attempt, or the context error if ctx is
+// done while waiting.
+func (p *RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	policy := *p
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= policy.MaxAttempts || !policy.Retryable(err) {
+			return err
+		}
+
+		delay := policy.delay(attempt, err)
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return err
+		}
+
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, err, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("retry canceled after %d attempts (last error: %v): %w", attempt, err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// delay returns the delay before retrying a failed attempt.
+func (p *RetryPolicy) delay(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+
+	backoff := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	backoff = math.Min(backoff, float64(p.MaxBackoff))
+	if !p.DisableJitter {
+		backoff *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(backoff)
+}
+
+// WithRetry wraps a provider so that its Chat calls are retried
+// according to policy.
+//
+// If policy is nil, the default policy is used. Streams returned by
+// ChatStream are retried only while opening; errors in the middle of a
+// stream are returned to the caller.
+func WithRetry(provider Provider, policy *RetryPolicy) Provider {
+	if policy == nil {
+		policy = &RetryPolicy{}
+	}
+	return &retryProvider{Provider: provider, policy: policy}
+}
+
+// retryProvider retries the calls of the wrapped provider.
+type retryProvider struct {
+	Provider
+	policy *RetryPolicy
+}
+
+// Chat performs LLM chat completion, retrying failed attempts.
+func (p *retryProvider) Chat(ctx context.Context, opts *Options) (*StandardResult, error) {
+	var result *StandardResult
+	err := p.policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = p.Provider.Chat(ctx, opts)
+		return err
+	})
+	return result, err
+}
+
+// Unwrap returns the wrapped provider.
+func (p *retryProvider) Unwrap() Provider {
+	return p.Provider
+}
+
+// ChatStream opens a stream, retrying failed attempts.
+func (p *retryProvider) ChatStream(ctx context.Context, opts *Options) (*Stream, error) {
+	streamer, ok := p.Provider.(StreamProvider)
+	if !ok {
+		result, err := p.Chat(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		return resultStream(result), nil
+	}
+
+	var stream *Stream
+	err := p.policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		stream, err = streamer.ChatStream(ctx, opts)
+		return err
+	})
+	return stream, err
+}
+
+// ChatWithRetry is like Chat, but retries rate limits, overloads, server
+// errors and transient network errors according to policy.
+//
+// If policy is nil, the default policy is used.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+//	defer cancel()
+//
+//	result, err := llm.ChatWithRetry(ctx, "claude", opts, &llm.RetryPolicy{MaxAttempts: 6})
+//	if errors.Is(err, llm.ErrOverloaded) {
+//	    // still overloaded after 6 attempts
+//	}
+func ChatWithRetry(ctx context.Context, providerName string, opts *Options, policy *RetryPolicy) (*StandardResult, error) {
+	provider, err := Get(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateFor(provider, opts); err != nil {
+		return nil, err
+	}
+
+	result, err := WithRetry(provider, policy).Chat(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("chat failed: %w", err)
+	}
+	recordUsage(ctx, provider.Name(), opts, result)
+
+	if err := checkOutput(opts, result); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
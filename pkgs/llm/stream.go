@@ -0,0 +1,253 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StreamProvider is implemented by providers that can stream responses.
+//
+// Streaming is optional: ChatStream falls back to Chat for providers that
+// do not implement it.
+type StreamProvider interface {
+	Provider
+
+	// ChatStream performs LLM chat completion and streams the response.
+	//
+	// The returned stream must be closed by the caller.
+	ChatStream(ctx context.Context, opts *Options) (*Stream, error)
+}
+
+// StreamChunk is a piece of a streamed response.
+//
+// Most chunks only carry a Content delta. ToolCalls, FinishReason, Model and
+// Usage are set by the chunks that report them, typically at the end of the
+// stream.
+type StreamChunk struct {
+	// Content is the text generated since the previous chunk.
+	Content string `json:"content,omitempty"`
+
+	// ToolCalls are tool calls completed since the previous chunk.
+	// Providers emit each call once its arguments are complete.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+
+	// FinishReason indicates why the generation stopped.
+	FinishReason string `json:"finish_reason,omitempty"`
+
+	// Model is the actual model used.
+	Model string `json:"model,omitempty"`
+
+	// Usage contains token usage information, if reported by this chunk.
+	// Fields that are zero are not reported and keep their previous value.
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+// Stream is a streamed LLM response.
+//
+// Use Next to iterate over the chunks, then Err to check for errors and
+// Result for the complete response:
+//
+//	stream, err := llm.ChatStream(ctx, "openai", opts)
+//	if err != nil {
+//	    return err
+//	}
+//	defer stream.Close()
+//
+//	for stream.Next() {
+//	    fmt.Print(stream.Chunk().Content)
+//	}
+//	if err := stream.Err(); err != nil {
+//	    return err
+//	}
+//	fmt.Println(stream.Result().Usage.TotalTokens)
+//
+// A Stream is not safe for concurrent use.
+type Stream struct {
+	recv   func() (*StreamChunk, error)
+	closer io.Closer
+
+	chunk   *StreamChunk
+	content strings.Builder
+	result  StandardResult
+	err     error
+	done    bool
+}
+
+// NewStream creates a stream from a receive function.
+//
+// recv returns the next chunk, or io.EOF at the end of the stream.
+// closer releases the underlying connection and may be nil.
+// This function is intended for provider implementations.
+func NewStream(recv func() (*StreamChunk, error), closer io.Closer) *Stream {
+	return &Stream{recv: recv, closer: closer}
+}
+
+// Next advances to the next chunk.
+//
+// Returns false at the end of the stream or on error.
+func (s *Stream) Next() bool {
+	if s.done {
+		return false
+	}
+
+	chunk, err := s.recv()
+	if err != nil {
+		s.done = true
+		s.chunk = nil
+		if !errors.Is(err, io.EOF) {
+			s.err = err
+		}
+		s.Close()
+		return false
+	}
+
+	s.chunk = chunk
+	s.add(chunk)
+	return true
+}
+
+// Chunk returns the current chunk.
+func (s *Stream) Chunk() *StreamChunk {
+	return s.chunk
+}
+
+// Err returns the error that ended the stream, if any.
+func (s *Stream) Err() error {
+	return s.err
+}
+
+// Result returns the response accumulated so far.
+//
+// After Next returns false without error, it is the complete response
+// including the usage reported at the end of the stream.
+func (s *Stream) Result() *StandardResult {
+	result := s.result
+	result.Content = s.content.String()
+	return &result
+}
+
+// Close releases the resources of the stream.
+// It is safe to call Close more than once.
+func (s *Stream) Close() error {
+	s.done = true
+	if s.closer == nil {
+		return nil
+	}
+	closer := s.closer
+	s.closer = nil
+	return closer.Close()
+}
+
+// add merges a chunk into the accumulated result.
+func (s *Stream) add(chunk *StreamChunk) {
+	s.content.WriteString(chunk.Content)
+	s.result.ToolCalls = append(s.result.ToolCalls, chunk.ToolCalls...)
+	if chunk.FinishReason != "" {
+		s.result.FinishReason = chunk.FinishReason
+	}
+	if chunk.Model != "" {
+		s.result.Model = chunk.Model
+	}
+	if u := chunk.Usage; u != nil {
+		if u.PromptTokens > 0 {
+			s.result.Usage.PromptTokens = u.PromptTokens
+		}
+		if u.CachedPromptTokens > 0 {
+			s.result.Usage.CachedPromptTokens = u.CachedPromptTokens
+		}
+		if u.CompletionTokens > 0 {
+			s.result.Usage.CompletionTokens = u.CompletionTokens
+		}
+		if u.TotalTokens > 0 {
+			s.result.Usage.TotalTokens = u.TotalTokens
+		} else {
+			s.result.Usage.TotalTokens = s.result.Usage.PromptTokens + s.result.Usage.CompletionTokens
+		}
+	}
+}
+
+// ChatStream is a convenience function that performs LLM chat completion
+// and streams the response.
+//
+// Providers that do not implement StreamProvider are called with Chat and
+// their response is returned as a single chunk.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeout
+//   - providerName: Name of the provider to use (e.g., "openai", "claude", "gemini")
+//   - opts: Provider options including API key, model, messages, etc.
+//
+// Returns the stream or an error. The stream must be closed by the caller.
+func ChatStream(ctx context.Context, providerName string, opts *Options) (*Stream, error) {
+	provider, err := Get(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateFor(provider, opts); err != nil {
+		return nil, err
+	}
+
+	streamer, ok := provider.(StreamProvider)
+	if !ok {
+		result, err := provider.Chat(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("chat failed: %w", err)
+		}
+		recordUsage(ctx, provider.Name(), opts, result)
+		return resultStream(result), nil
+	}
+
+	stream, err := streamer.ChatStream(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("chat stream failed: %w", err)
+	}
+	stream.onComplete(func(result *StandardResult) {
+		recordUsage(ctx, provider.Name(), opts, result)
+	})
+
+	return stream, nil
+}
+
+// onComplete arranges for fn to be called with the complete response when
+// the stream is closed after being read to the end without error.
+func (s *Stream) onComplete(fn func(result *StandardResult)) {
+	complete := false
+	recv := s.recv
+	s.recv = func() (*StreamChunk, error) {
+		chunk, err := recv()
+		if errors.Is(err, io.EOF) {
+			complete = true
+		}
+		return chunk, err
+	}
+	s.closer = &releaseCloser{closer: s.closer, release: func() {
+		if complete {
+			fn(s.Result())
+		}
+	}}
+}
+
+// resultStream returns a stream with a single chunk holding result.
+func resultStream(result *StandardResult) *Stream {
+	sent := false
+	usage := result.Usage
+	stream := NewStream(func() (*StreamChunk, error) {
+		if sent {
+			return nil, io.EOF
+		}
+		sent = true
+		return &StreamChunk{
+			Content:      result.Content,
+			ToolCalls:    result.ToolCalls,
+			FinishReason: result.FinishReason,
+			Model:        result.Model,
+			Usage:        &usage,
+		}, nil
+	}, nil)
+	stream.result.Raw = result.Raw
+	return stream
+}
@@ -0,0 +1,418 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrSchemaMismatch means the model returned output that is not valid JSON
+// or does not conform to the requested schema.
+var ErrSchemaMismatch = errors.New("output does not match schema")
+
+// defaultSchemaName is the name used when Schema.Name is empty.
+const defaultSchemaName = "response"
+
+// schemaNamePattern matches the schema names accepted by all providers.
+var schemaNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Schema requests structured JSON output conforming to a JSON Schema.
+//
+// Providers map it to their native mechanism:
+//   - OpenAI: response_format with type "json_schema"
+//   - Gemini: generationConfig.responseSchema
+//   - Claude: a single tool whose input is the schema, forced with tool_choice
+//
+// Example:
+//
+//	opts.Schema = &llm.Schema{
+//	    Name: "cues",
+//	    Schema: map[string]interface{}{
+//	        "type": "array",
+//	        "items": map[string]interface{}{
+//	            "type": "object",
+//	            "properties": map[string]interface{}{
+//	                "id":   map[string]interface{}{"type": "integer"},
+//	                "text": map[string]interface{}{"type": "string"},
+//	            },
+//	            "required": []string{"id", "text"},
+//	        },
+//	    },
+//	}
+type Schema struct {
+	// Name identifies the schema. It must contain only letters, digits,
+	// underscores and dashes (at most 64 characters).
+	// Default: "response"
+	Name string
+
+	// Description tells the model what the output is for. Optional.
+	Description string
+
+	// Schema is the JSON Schema document the output must conform to.
+	//
+	// Supported keywords for validation: type, properties, required,
+	// additionalProperties, items, enum, const, anyOf, minItems, maxItems,
+	// minLength, maxLength, minimum and maximum. Other keywords are sent
+	// to the provider but not checked locally.
+	Schema map[string]interface{}
+
+	// Strict asks the provider to enforce the schema exactly, where
+	// supported (OpenAI strict mode). Strict schemas must list every
+	// property as required and disallow additional properties.
+	Strict bool
+}
+
+// Validate validates the schema and sets default values.
+func (s *Schema) Validate() error {
+	if s.Name == "" {
+		s.Name = defaultSchemaName
+	}
+	if !schemaNamePattern.MatchString(s.Name) {
+		return &ValidationError{Field: "Schema.Name", Message: "must contain only letters, digits, '_' and '-' (at most 64)"}
+	}
+	if len(s.Schema) == 0 {
+		return &ValidationError{Field: "Schema.Schema", Message: "JSON schema is required"}
+	}
+	return nil
+}
+
+// Wrapped reports whether the schema's root is not an object.
+//
+// Some providers (Claude tool input, OpenAI strict mode) only accept object
+// schemas. Such schemas are sent wrapped in an object with a single "value"
+// property, and providers unwrap the output before returning it from Chat.
+// Streamed chunks carry the wrapped object; use Unwrap on the content of
+// the complete stream result.
+func (s *Schema) Wrapped() bool {
+	return s.Schema["type"] != "object"
+}
+
+// ObjectSchema returns the schema to send to providers that require an
+// object root. See Wrapped.
+func (s *Schema) ObjectSchema() map[string]interface{} {
+	if !s.Wrapped() {
+		return s.Schema
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           map[string]interface{}{"value": s.Schema},
+		"required":             []string{"value"},
+		"additionalProperties": false,
+	}
+}
+
+// Unwrap extracts the output from the object sent for a wrapped schema.
+// See Wrapped.
+func (s *Schema) Unwrap(content string) (string, error) {
+	if !s.Wrapped() {
+		return content, nil
+	}
+	var wrapper struct {
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal([]byte(content), &wrapper); err != nil {
+		return "", &SchemaError{Message: "invalid JSON: " + err.Error()}
+	}
+	return string(wrapper.Value), nil
+}
+
+// SchemaError reports where structured output violates its schema.
+type SchemaError struct {
+	// Path locates the offending value, e.g. "$[3].text".
+	Path string
+
+	// Message describes the violation.
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	if e.Path == "" {
+		return "schema error: " + e.Message
+	}
+	return "schema error at " + e.Path + ": " + e.Message
+}
+
+func (e *SchemaError) Unwrap() error {
+	return ErrSchemaMismatch
+}
+
+// ValidateJSON checks that data is valid JSON conforming to the schema.
+//
+// Returns a *SchemaError for the first violation found.
+func (s *Schema) ValidateJSON(data []byte) error {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return &SchemaError{Message: "invalid JSON: " + err.Error()}
+	}
+	if decoder.More() {
+		return &SchemaError{Message: "invalid JSON: unexpected data after value"}
+	}
+	return validateValue("$", s.Schema, value)
+}
+
+// Decode validates the content of result against schema and decodes it
+// into a value of type T.
+//
+// If schema is nil, the content is decoded without validation.
+//
+// Example:
+//
+//	type cue struct {
+//	    ID   int    `json:"id"`
+//	    Text string `json:"text"`
+//	}
+//
+//	result, err := llm.Chat(ctx, "openai", opts)
+//	if err != nil {
+//	    return err
+//	}
+//	cues, err := llm.Decode[[]cue](result, opts.Schema)
+func Decode[T any](result *StandardResult, schema *Schema) (T, error) {
+	var value T
+	data := []byte(result.Content)
+	if schema != nil {
+		if err := schema.ValidateJSON(data); err != nil {
+			return value, err
+		}
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return value, &SchemaError{Message: "failed to decode: " + err.Error()}
+	}
+	return value, nil
+}
+
+// checkOutput validates the result of a structured output request.
+func checkOutput(opts *Options, result *StandardResult) error {
+	if opts.Schema == nil {
+		return nil
+	}
+	return opts.Schema.ValidateJSON([]byte(result.Content))
+}
+
+// validateValue checks value against a JSON Schema node.
+func validateValue(path string, schema map[string]interface{}, value interface{}) error {
+	if options, ok := toSlice(schema["anyOf"]); ok {
+		matched := false
+		for _, option := range options {
+			sub, _ := option.(map[string]interface{})
+			if validateValue(path, sub, value) == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return &SchemaError{Path: path, Message: "does not match any schema in anyOf"}
+		}
+	}
+
+	if t, ok := schema["type"]; ok && !matchesType(t, value) {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("expected %v, got %s", t, jsonType(value))}
+	}
+
+	if enum, ok := toSlice(schema["enum"]); ok {
+		found := false
+		for _, e := range enum {
+			if jsonEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return &SchemaError{Path: path, Message: fmt.Sprintf("must be one of %v", enum)}
+		}
+	}
+
+	if c, ok := schema["const"]; ok && !jsonEqual(c, value) {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("must be %v", c)}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return validateObject(path, schema, v)
+	case []interface{}:
+		return validateArray(path, schema, v)
+	case string:
+		n := utf8.RuneCountInString(v)
+		if limit, ok := toFloat(schema["minLength"]); ok && float64(n) < limit {
+			return &SchemaError{Path: path, Message: fmt.Sprintf("must have at least %v characters", limit)}
+		}
+		if limit, ok := toFloat(schema["maxLength"]); ok && float64(n) > limit {
+			return &SchemaError{Path: path, Message: fmt.Sprintf("must have at most %v characters", limit)}
+		}
+	case json.Number:
+		n, _ := v.Float64()
+		if limit, ok := toFloat(schema["minimum"]); ok && n < limit {
+			return &SchemaError{Path: path, Message: fmt.Sprintf("must be at least %v", limit)}
+		}
+		if limit, ok := toFloat(schema["maximum"]); ok && n > limit {
+			return &SchemaError{Path: path, Message: fmt.Sprintf("must be at most %v", limit)}
+		}
+	}
+
+	return nil
+}
+
+// validateObject checks the properties of an object.
+func validateObject(path string, schema map[string]interface{}, value map[string]interface{}) error {
+	required, _ := toSlice(schema["required"])
+	for _, r := range required {
+		name, _ := r.(string)
+		if _, ok := value[name]; !ok {
+			return &SchemaError{Path: path, Message: fmt.Sprintf("missing required property %q", name)}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	// Check in a stable order so that errors are reproducible
+	keys := make([]string, 0, len(value))
+	for k := range value {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		propPath := path + "." + k
+		if sub, ok := properties[k].(map[string]interface{}); ok {
+			if err := validateValue(propPath, sub, value[k]); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return &SchemaError{Path: path, Message: fmt.Sprintf("unexpected property %q", k)}
+			}
+		case map[string]interface{}:
+			if err := validateValue(propPath, additional, value[k]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateArray checks the items of an array.
+func validateArray(path string, schema map[string]interface{}, value []interface{}) error {
+	if limit, ok := toFloat(schema["minItems"]); ok && float64(len(value)) < limit {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("must have at least %v items", limit)}
+	}
+	if limit, ok := toFloat(schema["maxItems"]); ok && float64(len(value)) > limit {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("must have at most %v items", limit)}
+	}
+
+	items, ok := schema["items"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	for i, item := range value {
+		if err := validateValue(fmt.Sprintf("%s[%d]", path, i), items, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchesType reports whether value has one of the JSON Schema types in t,
+// which is a type name or a list of type names.
+func matchesType(t interface{}, value interface{}) bool {
+	if names, ok := toSlice(t); ok {
+		for _, name := range names {
+			if matchesType(name, value) {
+				return true
+			}
+		}
+		return false
+	}
+
+	name, _ := t.(string)
+	actual := jsonType(value)
+	switch name {
+	case "number":
+		return actual == "integer" || actual == "number"
+	default:
+		return name == actual
+	}
+}
+
+// jsonType returns the JSON Schema type name of a decoded value.
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case json.Number:
+		if f, err := v.Float64(); err == nil && f == math.Trunc(f) && !strings.ContainsAny(v.String(), ".eE") {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// toSlice converts a schema list, which may be written as []interface{} or
+// []string in Go, to []interface{}.
+func toSlice(v interface{}) ([]interface{}, bool) {
+	if v == nil {
+		return nil, false
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil, false
+	}
+	result := make([]interface{}, rv.Len())
+	for i := range result {
+		result[i] = rv.Index(i).Interface()
+	}
+	return result, true
+}
+
+// toFloat converts a numeric schema keyword to float64.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// jsonEqual compares a schema constant with a decoded value by their JSON
+// encodings, so that Go and JSON numbers compare equal.
+func jsonEqual(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	var na, nb interface{}
+	if json.Unmarshal(ja, &na) != nil || json.Unmarshal(jb, &nb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(na, nb)
+}
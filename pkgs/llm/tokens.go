@@ -0,0 +1,322 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Token estimates for content that is not plain text.
+const (
+	// messageOverheadTokens is the estimated cost of the role and
+	// delimiters of each message.
+	messageOverheadTokens = 4
+
+	// mediaPartTokens is the estimated cost of an image, audio or file
+	// part. Actual costs depend on the size and duration of the media.
+	mediaPartTokens = 1000
+)
+
+// Tokenizer estimates the number of tokens in text for a model.
+type Tokenizer func(model, text string) int
+
+// ModelCapabilities describes the limits of a model.
+type ModelCapabilities struct {
+	// ContextWindow is the maximum number of tokens of the prompt and
+	// output together.
+	ContextWindow int
+
+	// MaxOutputTokens is the maximum number of tokens the model can
+	// generate in one response.
+	MaxOutputTokens int
+}
+
+// tokenRegistry holds the tokenizers and model capabilities.
+var tokenRegistry = struct {
+	mu           sync.RWMutex
+	tokenizers   map[string]Tokenizer
+	capabilities map[string]map[string]ModelCapabilities
+}{
+	tokenizers: make(map[string]Tokenizer),
+	capabilities: map[string]map[string]ModelCapabilities{
+		"openai": {
+			"gpt-3.5-turbo": {ContextWindow: 16385, MaxOutputTokens: 4096},
+			"gpt-4":         {ContextWindow: 8192, MaxOutputTokens: 8192},
+			"gpt-4-turbo":   {ContextWindow: 128000, MaxOutputTokens: 4096},
+			"gpt-4o":        {ContextWindow: 128000, MaxOutputTokens: 16384},
+			"gpt-4o-mini":   {ContextWindow: 128000, MaxOutputTokens: 16384},
+			"gpt-4.1":       {ContextWindow: 1047576, MaxOutputTokens: 32768},
+			"o1":            {ContextWindow: 200000, MaxOutputTokens: 100000},
+			"o3":            {ContextWindow: 200000, MaxOutputTokens: 100000},
+			"o4-mini":       {ContextWindow: 200000, MaxOutputTokens: 100000},
+		},
+		"claude": {
+			"claude-3-haiku":    {ContextWindow: 200000, MaxOutputTokens: 4096},
+			"claude-3-sonnet":   {ContextWindow: 200000, MaxOutputTokens: 4096},
+			"claude-3-opus":     {ContextWindow: 200000, MaxOutputTokens: 4096},
+			"claude-3-5-haiku":  {ContextWindow: 200000, MaxOutputTokens: 8192},
+			"claude-3-5-sonnet": {ContextWindow: 200000, MaxOutputTokens: 8192},
+			"claude-3-7-sonnet": {ContextWindow: 200000, MaxOutputTokens: 64000},
+			"claude-sonnet-4":   {ContextWindow: 200000, MaxOutputTokens: 64000},
+			"claude-opus-4":     {ContextWindow: 200000, MaxOutputTokens: 32000},
+		},
+		"gemini": {
+			"gemini-pro":       {ContextWindow: 32760, MaxOutputTokens: 8192},
+			"gemini-1.5-flash": {ContextWindow: 1048576, MaxOutputTokens: 8192},
+			"gemini-1.5-pro":   {ContextWindow: 2097152, MaxOutputTokens: 8192},
+			"gemini-2.0-flash": {ContextWindow: 1048576, MaxOutputTokens: 8192},
+			"gemini-2.5-flash": {ContextWindow: 1048576, MaxOutputTokens: 65536},
+			"gemini-2.5-pro":   {ContextWindow: 1048576, MaxOutputTokens: 65536},
+		},
+	},
+}
+
+// RegisterTokenizer sets the tokenizer used to estimate tokens for a
+// provider, replacing the default HeuristicTokens.
+//
+// This function is safe for concurrent use.
+func RegisterTokenizer(provider string, tokenizer Tokenizer) {
+	tokenRegistry.mu.Lock()
+	defer tokenRegistry.mu.Unlock()
+	tokenRegistry.tokenizers[provider] = tokenizer
+}
+
+// RegisterModel sets the capabilities of a model of a provider.
+//
+// model may be a prefix: "gpt-4o" also applies to "gpt-4o-2024-08-06"
+// unless a longer prefix is registered. This function is safe for
+// concurrent use.
+func RegisterModel(provider, model string, capabilities ModelCapabilities) {
+	tokenRegistry.mu.Lock()
+	defer tokenRegistry.mu.Unlock()
+
+	models, ok := tokenRegistry.capabilities[provider]
+	if !ok {
+		models = make(map[string]ModelCapabilities)
+		tokenRegistry.capabilities[provider] = models
+	}
+	models[model] = capabilities
+}
+
+// LookupModel returns the capabilities of a model of a provider, matching
+// the longest registered prefix of the model name.
+//
+// Returns false if the model is unknown.
+func LookupModel(provider, model string) (ModelCapabilities, bool) {
+	tokenRegistry.mu.RLock()
+	defer tokenRegistry.mu.RUnlock()
+
+	return longestPrefix(tokenRegistry.capabilities[provider], model)
+}
+
+// longestPrefix returns the value of the longest key of m that is a prefix
+// of model.
+func longestPrefix[T any](m map[string]T, model string) (T, bool) {
+	var best string
+	var found T
+	ok := false
+	for name, value := range m {
+		if strings.HasPrefix(model, name) && (!ok || len(name) > len(best)) {
+			best, found, ok = name, value, true
+		}
+	}
+	return found, ok
+}
+
+// EstimateTokens estimates the number of tokens in text for a model of a
+// provider, using the provider's registered tokenizer or HeuristicTokens.
+func EstimateTokens(provider, model, text string) int {
+	tokenRegistry.mu.RLock()
+	tokenizer, ok := tokenRegistry.tokenizers[provider]
+	tokenRegistry.mu.RUnlock()
+
+	if ok {
+		return tokenizer(model, text)
+	}
+	return HeuristicTokens(text)
+}
+
+// HeuristicTokens estimates the number of tokens in text without a
+// tokenizer.
+//
+// CJK characters (Han, kana and Hangul) count as one token each, runs of
+// other letters and digits as one token per four characters, and other
+// symbols as one token each. Whitespace is free. The estimate errs on the
+// high side for typical BPE tokenizers.
+func HeuristicTokens(text string) int {
+	tokens := 0
+	run := 0
+	flush := func() {
+		tokens += (run + 3) / 4
+		run = 0
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flush()
+			tokens++
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			run++
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
+
+// isCJK reports whether r is a Chinese, Japanese or Korean character.
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// EstimateRequestTokens estimates the number of prompt tokens of a request
+// to provider: the system prompt, messages, tools and schema.
+func EstimateRequestTokens(provider string, opts *Options) int {
+	tokens := 0
+	if opts.SystemPrompt != "" {
+		tokens += messageOverheadTokens + EstimateTokens(provider, opts.Model, opts.SystemPrompt)
+	}
+	for _, msg := range opts.Messages {
+		tokens += estimateMessageTokens(provider, opts.Model, msg)
+	}
+	if len(opts.Tools) > 0 {
+		data, _ := json.Marshal(opts.Tools)
+		tokens += EstimateTokens(provider, opts.Model, string(data))
+	}
+	if opts.Schema != nil {
+		data, _ := json.Marshal(opts.Schema.Schema)
+		tokens += EstimateTokens(provider, opts.Model, string(data))
+	}
+	return tokens
+}
+
+// estimateMessageTokens estimates the number of tokens of one message.
+func estimateMessageTokens(provider, model string, msg Message) int {
+	tokens := messageOverheadTokens
+	for _, part := range msg.ContentParts() {
+		if part.Type == PartText {
+			tokens += EstimateTokens(provider, model, part.Text)
+		} else {
+			tokens += mediaPartTokens
+		}
+	}
+	for _, call := range msg.ToolCalls {
+		tokens += EstimateTokens(provider, model, call.Name+string(call.Arguments))
+	}
+	return tokens
+}
+
+// Budget is the token budget of a request.
+type Budget struct {
+	// ContextWindow is the context window of the model.
+	ContextWindow int
+
+	// PromptTokens is the estimated size of the prompt.
+	PromptTokens int
+
+	// MaxTokens is the number of tokens reserved for the output:
+	// Options.MaxTokens, or 0 if it is not set.
+	MaxTokens int
+}
+
+// Fits reports whether the prompt and output fit in the context window.
+func (b Budget) Fits() bool {
+	return b.PromptTokens+b.MaxTokens <= b.ContextWindow
+}
+
+// CheckBudget estimates whether a request to provider fits in the context
+// window of its model, before paying for the prompt upload.
+//
+// Returns an error wrapping ErrContextLength if it does not fit, or if
+// MaxTokens exceeds the model's output limit. Requests to models that are
+// not in the capability table (see RegisterModel) always pass, with a zero
+// ContextWindow in the budget.
+func CheckBudget(provider string, opts *Options) (Budget, error) {
+	budget := Budget{
+		PromptTokens: EstimateRequestTokens(provider, opts),
+		MaxTokens:    opts.MaxTokens,
+	}
+
+	capabilities, ok := LookupModel(provider, opts.Model)
+	if !ok || capabilities.ContextWindow == 0 {
+		return budget, nil
+	}
+	budget.ContextWindow = capabilities.ContextWindow
+
+	if capabilities.MaxOutputTokens > 0 && opts.MaxTokens > capabilities.MaxOutputTokens {
+		return budget, fmt.Errorf("max tokens %d exceed the output limit %d of %s: %w",
+			opts.MaxTokens, capabilities.MaxOutputTokens, opts.Model, ErrContextLength)
+	}
+	if !budget.Fits() {
+		return budget, fmt.Errorf("estimated %d prompt tokens + %d max tokens exceed the context window %d of %s: %w",
+			budget.PromptTokens, budget.MaxTokens, budget.ContextWindow, opts.Model, ErrContextLength)
+	}
+	return budget, nil
+}
+
+// TrimToBudget drops the oldest messages of opts until the request fits in
+// the context window of its model, and returns the number of messages
+// dropped.
+//
+// System messages and the last message are never dropped. Tool results
+// and assistant messages left at the start of the conversation are
+// dropped with the messages they answer. Returns an error wrapping
+// ErrContextLength if the request still does not fit.
+func TrimToBudget(provider string, opts *Options) (int, error) {
+	dropped := 0
+	for {
+		_, err := CheckBudget(provider, opts)
+		if err == nil {
+			return dropped, nil
+		}
+		// Dropping messages cannot fix an output limit error
+		if capabilities, ok := LookupModel(provider, opts.Model); ok &&
+			capabilities.MaxOutputTokens > 0 && opts.MaxTokens > capabilities.MaxOutputTokens {
+			return dropped, err
+		}
+
+		n := dropOldest(opts)
+		if n == 0 {
+			return dropped, err
+		}
+		dropped += n
+	}
+}
+
+// dropOldest drops the oldest non-system message and the tool results and
+// assistant messages that follow it, keeping the last message.
+//
+// Returns the number of messages dropped.
+func dropOldest(opts *Options) int {
+	messages := opts.Messages
+	first := -1
+	for i, msg := range messages {
+		if msg.Role != "system" {
+			first = i
+			break
+		}
+	}
+	if first < 0 || first >= len(messages)-1 {
+		return 0
+	}
+
+	// Drop until the next message that can start a conversation
+	end := first + 1
+	for end < len(messages)-1 && (messages[end].Role == "tool" || messages[end].Role == "assistant") {
+		end++
+	}
+
+	kept := make([]Message, 0, len(messages)-(end-first))
+	kept = append(kept, messages[:first]...)
+	kept = append(kept, messages[end:]...)
+	opts.Messages = kept
+	return end - first
+}
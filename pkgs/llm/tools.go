@@ -0,0 +1,239 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Tool describes a function the model may call.
+type Tool struct {
+	// Name identifies the tool. It must contain only letters, digits,
+	// underscores and dashes (at most 64 characters).
+	Name string `json:"name"`
+
+	// Description tells the model what the tool does and when to call it.
+	Description string `json:"description,omitempty"`
+
+	// Parameters is the JSON Schema of the tool's arguments, which must be
+	// an object. If nil, the tool takes no arguments.
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// ParametersSchema returns the schema of the tool's arguments, defaulting
+// to an empty object. This function is intended for provider
+// implementations.
+func (t *Tool) ParametersSchema() map[string]interface{} {
+	if t.Parameters == nil {
+		return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	return t.Parameters
+}
+
+// ToolCall is a call of a tool requested by the model.
+type ToolCall struct {
+	// ID identifies the call. Answer it with a "tool" message whose
+	// ToolCallID is this ID.
+	ID string `json:"id"`
+
+	// Name is the name of the tool to call.
+	Name string `json:"name"`
+
+	// Arguments is the JSON object of arguments.
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// ToolResult returns the "tool" message answering call with content.
+func ToolResult(call ToolCall, content string) Message {
+	return Message{Role: "tool", Name: call.Name, Content: content, ToolCallID: call.ID}
+}
+
+// validateTools validates the tool options and tool messages.
+func validateTools(o *Options) error {
+	names := make(map[string]bool, len(o.Tools))
+	for _, tool := range o.Tools {
+		if !schemaNamePattern.MatchString(tool.Name) {
+			return &ValidationError{Field: "Tools", Message: fmt.Sprintf("invalid tool name %q", tool.Name)}
+		}
+		if names[tool.Name] {
+			return &ValidationError{Field: "Tools", Message: fmt.Sprintf("duplicate tool %q", tool.Name)}
+		}
+		names[tool.Name] = true
+	}
+
+	switch o.ToolChoice {
+	case "", "auto", "none", "required":
+	default:
+		if !names[o.ToolChoice] {
+			return &ValidationError{Field: "ToolChoice", Message: fmt.Sprintf("unknown tool %q", o.ToolChoice)}
+		}
+	}
+
+	for _, msg := range o.Messages {
+		if msg.Role == "tool" && msg.ToolCallID == "" {
+			return &ValidationError{Field: "Messages", Message: "tool messages require ToolCallID"}
+		}
+	}
+
+	return nil
+}
+
+// ToolFunc implements a tool. It receives the JSON arguments of a call and
+// returns the result passed back to the model.
+type ToolFunc func(ctx context.Context, arguments json.RawMessage) (string, error)
+
+// Toolbox holds the Go functions that implement tools, for use with
+// ChatWithTools.
+//
+// A Toolbox is safe for concurrent use.
+type Toolbox struct {
+	// MaxRounds is the maximum number of model calls in one run.
+	// Default: 8
+	MaxRounds int
+
+	mu    sync.RWMutex
+	tools []Tool
+	funcs map[string]ToolFunc
+}
+
+// Register adds a tool implemented by fn.
+//
+// If a tool with the same name already exists, it will be replaced.
+func (t *Toolbox) Register(tool Tool, fn ToolFunc) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.funcs == nil {
+		t.funcs = make(map[string]ToolFunc)
+	}
+	if _, ok := t.funcs[tool.Name]; ok {
+		for i := range t.tools {
+			if t.tools[i].Name == tool.Name {
+				t.tools[i] = tool
+			}
+		}
+	} else {
+		t.tools = append(t.tools, tool)
+	}
+	t.funcs[tool.Name] = fn
+}
+
+// Tools returns the definitions of the registered tools.
+func (t *Toolbox) Tools() []Tool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]Tool(nil), t.tools...)
+}
+
+// Call runs the tool requested by call.
+//
+// Unknown tools and tool errors are reported as the result, so that the
+// model can recover from them.
+func (t *Toolbox) Call(ctx context.Context, call ToolCall) Message {
+	t.mu.RLock()
+	fn, ok := t.funcs[call.Name]
+	t.mu.RUnlock()
+
+	if !ok {
+		return ToolResult(call, fmt.Sprintf("error: unknown tool %q", call.Name))
+	}
+
+	result, err := fn(ctx, call.Arguments)
+	if err != nil {
+		return ToolResult(call, "error: "+err.Error())
+	}
+	return ToolResult(call, result)
+}
+
+// Run calls the model with the registered tools until it stops calling
+// them, running the requested calls in between.
+//
+// Returns the final result, whose Usage sums all rounds, and the
+// conversation including the tool calls and results. opts is not modified.
+func (t *Toolbox) Run(ctx context.Context, provider Provider, opts *Options) (*StandardResult, []Message, error) {
+	maxRounds := t.MaxRounds
+	if maxRounds <= 0 {
+		maxRounds = 8
+	}
+
+	round := *opts
+	round.Tools = append(append([]Tool(nil), opts.Tools...), t.Tools()...)
+	round.Messages = append([]Message(nil), opts.Messages...)
+	if err := validateFor(provider, &round); err != nil {
+		return nil, nil, err
+	}
+
+	var usage Usage
+	for i := 0; i < maxRounds; i++ {
+		result, err := provider.Chat(ctx, &round)
+		if err != nil {
+			return nil, round.Messages, err
+		}
+		recordUsage(ctx, provider.Name(), &round, result)
+		usage.Add(result.Usage)
+
+		round.Messages = append(round.Messages, Message{
+			Role:      "assistant",
+			Content:   result.Content,
+			ToolCalls: result.ToolCalls,
+		})
+		if len(result.ToolCalls) == 0 {
+			result.Usage = usage
+			return result, round.Messages, nil
+		}
+
+		for _, call := range result.ToolCalls {
+			round.Messages = append(round.Messages, t.Call(ctx, call))
+		}
+
+		// Forcing a tool again would never let the model answer
+		if round.ToolChoice != "auto" && round.ToolChoice != "none" {
+			round.ToolChoice = ""
+		}
+	}
+
+	return nil, round.Messages, fmt.Errorf("model still calling tools after %d rounds", maxRounds)
+}
+
+// ChatWithTools is a convenience function that performs LLM chat completion
+// with the tools of toolbox, running the calls requested by the model until
+// it gives a final answer.
+//
+// Returns the final result and the complete conversation.
+//
+// Example:
+//
+//	toolbox := &llm.Toolbox{}
+//	toolbox.Register(llm.Tool{
+//	    Name:        "lookup_glossary",
+//	    Description: "Look up the approved translation of a term.",
+//	    Parameters: map[string]interface{}{
+//	        "type": "object",
+//	        "properties": map[string]interface{}{
+//	            "term": map[string]interface{}{"type": "string"},
+//	        },
+//	        "required": []string{"term"},
+//	    },
+//	}, func(ctx context.Context, args json.RawMessage) (string, error) {
+//	    var in struct{ Term string }
+//	    if err := json.Unmarshal(args, &in); err != nil {
+//	        return "", err
+//	    }
+//	    return glossary[in.Term], nil
+//	})
+//
+//	result, _, err := llm.ChatWithTools(ctx, "claude", opts, toolbox)
+func ChatWithTools(ctx context.Context, providerName string, opts *Options, toolbox *Toolbox) (*StandardResult, []Message, error) {
+	provider, err := Get(providerName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, messages, err := toolbox.Run(ctx, provider, opts)
+	if err != nil {
+		return nil, messages, fmt.Errorf("chat failed: %w", err)
+	}
+
+	return result, messages, nil
+}
@@ -0,0 +1,556 @@
+package subtitle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// assStyleFields is the field order of the [V4+ Styles] section.
+var assStyleFields = []string{
+	"Name", "Fontname", "Fontsize", "PrimaryColour", "SecondaryColour", "OutlineColour", "BackColour",
+	"Bold", "Italic", "Underline", "StrikeOut", "ScaleX", "ScaleY", "Spacing", "Angle",
+	"BorderStyle", "Outline", "Shadow", "Alignment", "MarginL", "MarginR", "MarginV", "Encoding",
+}
+
+// assEventFields is the field order of the [Events] section.
+var assEventFields = []string{
+	"Layer", "Start", "End", "Style", "Name", "MarginL", "MarginR", "MarginV", "Effect", "Text",
+}
+
+var (
+	// markupTagPattern matches the SRT-style inline tags used in cue lines.
+	markupTagPattern = regexp.MustCompile(`(?i)</?[ibus]>`)
+
+	// speakerLabelPattern matches a leading speaker or sound label such as
+	// "[JOHN]" or "- [door slams]".
+	speakerLabelPattern = regexp.MustCompile(`^(-\s*)?(\[[^\]]*\])`)
+
+	// overrideBlockPattern matches an ASS override block.
+	overrideBlockPattern = regexp.MustCompile(`\{[^}]*\}`)
+
+	// overrideTagPattern matches a single override tag mapped back to markup.
+	overrideTagPattern = regexp.MustCompile(`^([ibus])(\d*)$`)
+)
+
+// WriteASS writes a document in Advanced SubStation Alpha (.ass) format.
+//
+// The output contains the [Script Info], [V4+ Styles] and [Events] sections.
+// Styles are scaled from opts.StyleResY to opts.PlayResY. Inline markup is
+// mapped to override tags (<i> to {\i1}, <b> to {\b1}, ...) and leading
+// speaker labels such as "[JOHN]" are never italicized. The cue speaker is
+// written to the Name field.
+//
+// Bilingual cues are written as one event: the secondary lines switch to
+// opts.SecondaryStyle with a {\r} override and are stacked above or below
+// the primary lines according to opts.SecondaryOnTop.
+//
+// If opts is nil, a 1080p script with DefaultStyle() is written.
+func WriteASS(w io.Writer, doc *Document, opts *ASSOptions) error {
+	if opts == nil {
+		opts = &ASSOptions{}
+	}
+
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	styles := opts.Styles
+	if hasBilingualCues(doc) && !hasStyle(styles, opts.SecondaryStyle) {
+		secondary := DefaultSecondaryStyle()
+		secondary.Name = opts.SecondaryStyle
+		styles = append(styles[:len(styles):len(styles)], secondary)
+	}
+
+	bw := bufio.NewWriter(w)
+	writeASSScriptInfo(bw, opts)
+
+	// Styles
+	factor := float64(opts.PlayResY) / float64(opts.StyleResY)
+	bw.WriteString("\n[V4+ Styles]\n")
+	fmt.Fprintf(bw, "Format: %s\n", strings.Join(assStyleFields, ", "))
+	for _, style := range styles {
+		fmt.Fprintf(bw, "Style: %s\n", formatASSStyle(style.Scale(factor)))
+	}
+
+	// Events
+	defaultStyle := opts.Styles[0].Name
+	bw.WriteString("\n[Events]\n")
+	fmt.Fprintf(bw, "Format: %s\n", strings.Join(assEventFields, ", "))
+	for i := range doc.Cues {
+		cue := &doc.Cues[i]
+		style := cue.Style
+		if style == "" {
+			style = defaultStyle
+		}
+
+		text := toASSText(cue.Lines)
+		if cue.IsBilingual() {
+			text = toASSBilingualText(cue, opts.SecondaryStyle, opts.SecondaryOnTop)
+		}
+
+		fmt.Fprintf(bw, "Dialogue: 0,%s,%s,%s,%s,0,0,0,,%s\n",
+			formatASSTime(cue.Start), formatASSTime(cue.End), style,
+			strings.ReplaceAll(cue.Speaker, ",", " "), text)
+	}
+
+	return bw.Flush()
+}
+
+// hasStyle reports whether styles contains a style with the given name.
+func hasStyle(styles []Style, name string) bool {
+	for _, style := range styles {
+		if style.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// writeASSScriptInfo writes the [Script Info] section.
+func writeASSScriptInfo(bw *bufio.Writer, opts *ASSOptions) {
+	bw.WriteString("[Script Info]\n")
+	if opts.Title != "" {
+		fmt.Fprintf(bw, "Title: %s\n", opts.Title)
+	}
+	bw.WriteString("ScriptType: v4.00+\n")
+
+	headers := map[string]string{
+		"WrapStyle":             "0",
+		"ScaledBorderAndShadow": "yes",
+	}
+	for k, v := range opts.ScriptInfo {
+		headers[k] = v
+	}
+	headers["PlayResX"] = strconv.Itoa(opts.PlayResX)
+	headers["PlayResY"] = strconv.Itoa(opts.PlayResY)
+	delete(headers, "Title")
+	delete(headers, "ScriptType")
+
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(bw, "%s: %s\n", k, headers[k])
+	}
+}
+
+// formatASSStyle formats a style as the value of a Style line.
+func formatASSStyle(s Style) string {
+	fields := []string{
+		s.Name,
+		s.FontName,
+		formatASSFloat(s.FontSize),
+		s.PrimaryColour.String(),
+		s.SecondaryColour.String(),
+		s.OutlineColour.String(),
+		s.BackColour.String(),
+		formatASSBool(s.Bold),
+		formatASSBool(s.Italic),
+		formatASSBool(s.Underline),
+		formatASSBool(s.StrikeOut),
+		formatASSFloat(s.ScaleX),
+		formatASSFloat(s.ScaleY),
+		formatASSFloat(s.Spacing),
+		formatASSFloat(s.Angle),
+		strconv.Itoa(s.BorderStyle),
+		formatASSFloat(s.Outline),
+		formatASSFloat(s.Shadow),
+		strconv.Itoa(s.Alignment),
+		strconv.Itoa(s.MarginL),
+		strconv.Itoa(s.MarginR),
+		strconv.Itoa(s.MarginV),
+		strconv.Itoa(s.Encoding),
+	}
+	return strings.Join(fields, ",")
+}
+
+// formatASSFloat formats a number without trailing zeros.
+func formatASSFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// formatASSBool formats a boolean as ASS expects (-1 for true).
+func formatASSBool(v bool) string {
+	if v {
+		return "-1"
+	}
+	return "0"
+}
+
+// formatASSTime formats milliseconds as H:MM:SS.cc, rounded to centiseconds.
+func formatASSTime(ms int64) string {
+	if ms < 0 {
+		ms = 0
+	}
+	cs := (ms + 5) / 10
+	return fmt.Sprintf("%d:%02d:%02d.%02d", cs/360000, cs/6000%60, cs/100%60, cs%100)
+}
+
+// toASSText converts cue lines to ASS event text.
+func toASSText(lines []string) string {
+	italic := false
+	converted := make([]string, len(lines))
+
+	for i, line := range lines {
+		var b strings.Builder
+		labelStart, labelEnd := speakerLabelRange(line)
+		if labelStart > 0 && markupTagPattern.ReplaceAllString(line[:labelStart], "") == "" {
+			// Move a label out of the leading tags: "<i>[JOHN] ..." becomes "[JOHN]<i> ..."
+			line = line[labelStart:labelEnd] + line[:labelStart] + line[labelEnd:]
+			labelStart, labelEnd = 0, labelEnd-labelStart
+		}
+		tags := markupTagPattern.FindAllStringIndex(line, -1)
+
+		pos := 0
+		for pos < len(line) {
+			// Keep speaker labels upright even inside italic passages
+			if pos == labelStart {
+				if italic {
+					fmt.Fprintf(&b, `{\i0}%s{\i1}`, line[labelStart:labelEnd])
+				} else {
+					b.WriteString(line[labelStart:labelEnd])
+				}
+				pos = labelEnd
+				continue
+			}
+
+			if len(tags) > 0 && tags[0][0] == pos {
+				tag := strings.ToLower(line[tags[0][0]:tags[0][1]])
+				name := strings.Trim(tag, "</>")
+				if strings.HasPrefix(tag, "</") {
+					fmt.Fprintf(&b, `{\%s0}`, name)
+				} else {
+					fmt.Fprintf(&b, `{\%s1}`, name)
+				}
+				if name == "i" {
+					italic = !strings.HasPrefix(tag, "</")
+				}
+				pos = tags[0][1]
+				tags = tags[1:]
+				continue
+			}
+
+			next := len(line)
+			if len(tags) > 0 && tags[0][0] < next {
+				next = tags[0][0]
+			}
+			if labelStart > pos && labelStart < next {
+				next = labelStart
+			}
+			b.WriteString(line[pos:next])
+			pos = next
+		}
+
+		converted[i] = b.String()
+	}
+
+	return strings.Join(converted, `\N`)
+}
+
+// speakerLabelRange returns the byte range of a leading speaker label,
+// skipping any opening markup tags, or -1, -1 if the line has none.
+func speakerLabelRange(line string) (int, int) {
+	pos := 0
+	for {
+		loc := markupTagPattern.FindStringIndex(line[pos:])
+		if loc == nil || loc[0] != 0 {
+			break
+		}
+		pos += loc[1]
+	}
+
+	m := speakerLabelPattern.FindStringSubmatchIndex(line[pos:])
+	if m == nil {
+		return -1, -1
+	}
+	return pos + m[4], pos + m[5]
+}
+
+// fromASSText converts ASS event text to cue lines, mapping italic, bold,
+// underline and strike-out overrides back to markup and dropping all others.
+func fromASSText(text string) []string {
+	text = overrideBlockPattern.ReplaceAllStringFunc(text, func(block string) string {
+		var b strings.Builder
+		for _, tag := range strings.Split(strings.Trim(block, "{}"), `\`) {
+			m := overrideTagPattern.FindStringSubmatch(strings.TrimSpace(tag))
+			if m == nil {
+				continue
+			}
+			if m[2] == "" || m[2] == "0" {
+				fmt.Fprintf(&b, "</%s>", m[1])
+			} else {
+				fmt.Fprintf(&b, "<%s>", m[1])
+			}
+		}
+		return b.String()
+	})
+
+	text = strings.ReplaceAll(text, `\h`, " ")
+	text = strings.ReplaceAll(text, `\n`, `\N`)
+	return strings.Split(text, `\N`)
+}
+
+// ReadASS reads an Advanced SubStation Alpha (.ass) or SubStation Alpha
+// (.ssa) script.
+//
+// Dialogue events become cues: the Style field is stored in Cue.Style and
+// the Name field in Cue.Speaker. Italic, bold, underline and strike-out
+// overrides are mapped back to markup; all other override tags are dropped.
+// Comment events and unknown sections are ignored. Bilingual events written
+// by WriteASS are split back into primary and secondary lines.
+//
+// The script header and style sheet are returned as ASSOptions with
+// StyleResY set to the script's PlayResY, so passing them to WriteASS
+// reproduces the original styling.
+//
+// If opts is nil, lenient mode is used.
+func ReadASS(r io.Reader, opts *ReadOptions) (*Document, *ASSOptions, error) {
+	if opts == nil {
+		opts = &ReadOptions{}
+	}
+
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, nil, &ParseError{Format: "ass", Message: "failed to read input", Err: err}
+	}
+
+	doc := NewDocument("")
+	assOpts := &ASSOptions{ScriptInfo: make(map[string]string)}
+	var issues ParseErrors
+	report := func(line int, format string, args ...interface{}) {
+		issues = append(issues, &ParseError{Format: "ass", Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var (
+		section      string
+		legacyStyles bool
+		format       []string
+	)
+
+	for i, raw := range lines {
+		lineNo := i + 1
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(line)
+			legacyStyles = section == "[v4 styles]"
+			format = nil
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			if section != "" && section != "[fonts]" && section != "[graphics]" {
+				report(lineNo, "malformed line %q", line)
+			}
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		switch section {
+		case "[script info]":
+			switch key {
+			case "Title":
+				assOpts.Title = value
+			case "PlayResX":
+				assOpts.PlayResX, _ = strconv.Atoi(value)
+			case "PlayResY":
+				assOpts.PlayResY, _ = strconv.Atoi(value)
+			case "ScriptType":
+			default:
+				assOpts.ScriptInfo[key] = value
+			}
+
+		case "[v4+ styles]", "[v4 styles]", "[events]":
+			if key == "Format" {
+				format = splitASSFormat(value)
+				continue
+			}
+			if format == nil {
+				report(lineNo, "%s line before Format line", key)
+				continue
+			}
+
+			fields := strings.SplitN(value, ",", len(format))
+			if len(fields) != len(format) {
+				report(lineNo, "expected %d fields, got %d", len(format), len(fields))
+				continue
+			}
+			values := make(map[string]string, len(format))
+			for j, name := range format {
+				values[name] = fields[j]
+			}
+
+			switch {
+			case key == "Style" && section != "[events]":
+				style, err := parseASSStyle(values, legacyStyles)
+				if err != nil {
+					issues = append(issues, &ParseError{Format: "ass", Line: lineNo, Message: "invalid style", Err: err})
+					continue
+				}
+				assOpts.Styles = append(assOpts.Styles, style)
+
+			case key == "Dialogue" && section == "[events]":
+				cue, err := parseASSEvent(values)
+				if err != nil {
+					issues = append(issues, &ParseError{Format: "ass", Line: lineNo, Message: "invalid event", Err: err})
+					continue
+				}
+				if _, _, style, onTop, ok := splitASSBilingual(values["text"]); ok {
+					assOpts.SecondaryStyle = style
+					assOpts.SecondaryOnTop = onTop
+				}
+				doc.Add(cue)
+			}
+		}
+	}
+
+	assOpts.StyleResY = assOpts.PlayResY
+	if len(assOpts.ScriptInfo) == 0 {
+		assOpts.ScriptInfo = nil
+	}
+
+	if opts.Strict && len(issues) > 0 {
+		return nil, nil, issues
+	}
+
+	return doc, assOpts, nil
+}
+
+// splitASSFormat splits a Format line into lower-cased field names.
+func splitASSFormat(value string) []string {
+	fields := strings.Split(value, ",")
+	for i := range fields {
+		fields[i] = strings.ToLower(strings.TrimSpace(fields[i]))
+	}
+	return fields
+}
+
+// parseASSStyle builds a style from named fields.
+// SSA styles use legacy alignment values which are converted to numpad layout.
+func parseASSStyle(values map[string]string, legacy bool) (Style, error) {
+	var s Style
+	var err error
+
+	s.Name = strings.TrimSpace(values["name"])
+	s.FontName = strings.TrimSpace(values["fontname"])
+
+	floats := map[string]*float64{
+		"fontsize": &s.FontSize, "scalex": &s.ScaleX, "scaley": &s.ScaleY,
+		"spacing": &s.Spacing, "angle": &s.Angle, "outline": &s.Outline, "shadow": &s.Shadow,
+	}
+	for name, dst := range floats {
+		if v, ok := values[name]; ok {
+			if *dst, err = strconv.ParseFloat(strings.TrimSpace(v), 64); err != nil {
+				return s, fmt.Errorf("invalid %s: %w", name, err)
+			}
+		}
+	}
+
+	ints := map[string]*int{
+		"borderstyle": &s.BorderStyle, "alignment": &s.Alignment, "marginl": &s.MarginL,
+		"marginr": &s.MarginR, "marginv": &s.MarginV, "encoding": &s.Encoding,
+	}
+	for name, dst := range ints {
+		if v, ok := values[name]; ok {
+			if *dst, err = strconv.Atoi(strings.TrimSpace(v)); err != nil {
+				return s, fmt.Errorf("invalid %s: %w", name, err)
+			}
+		}
+	}
+
+	colours := map[string]*Color{
+		"primarycolour": &s.PrimaryColour, "secondarycolour": &s.SecondaryColour,
+		"outlinecolour": &s.OutlineColour, "backcolour": &s.BackColour,
+	}
+	if legacy {
+		// SSA names the outline colour TertiaryColour
+		colours["tertiarycolour"] = &s.OutlineColour
+	}
+	for name, dst := range colours {
+		if v, ok := values[name]; ok {
+			if *dst, err = ParseColor(v); err != nil {
+				return s, err
+			}
+		}
+	}
+
+	bools := map[string]*bool{
+		"bold": &s.Bold, "italic": &s.Italic, "underline": &s.Underline, "strikeout": &s.StrikeOut,
+	}
+	for name, dst := range bools {
+		if v, ok := values[name]; ok {
+			*dst = strings.TrimSpace(v) != "0"
+		}
+	}
+
+	if legacy {
+		s.Alignment = convertSSAAlignment(s.Alignment)
+		if s.ScaleX == 0 {
+			s.ScaleX = 100
+		}
+		if s.ScaleY == 0 {
+			s.ScaleY = 100
+		}
+	}
+
+	return s, nil
+}
+
+// convertSSAAlignment converts SSA alignment (1-3 bottom, 5-7 top, 9-11 middle)
+// to ASS numpad alignment.
+func convertSSAAlignment(a int) int {
+	switch {
+	case a >= 5 && a <= 7:
+		return a + 2
+	case a >= 9 && a <= 11:
+		return a - 5
+	}
+	return a
+}
+
+// parseASSEvent builds a cue from named Dialogue fields.
+func parseASSEvent(values map[string]string) (Cue, error) {
+	start, err := parseASSTime(values["start"])
+	if err != nil {
+		return Cue{}, err
+	}
+
+	end, err := parseASSTime(values["end"])
+	if err != nil {
+		return Cue{}, err
+	}
+
+	cue := Cue{
+		Start:   start,
+		End:     end,
+		Speaker: strings.TrimSpace(values["name"]),
+		Style:   strings.TrimSpace(strings.TrimPrefix(values["style"], "*")),
+	}
+
+	text := values["text"]
+	if primary, secondary, _, _, ok := splitASSBilingual(text); ok {
+		cue.Lines = fromASSText(primary)
+		cue.Secondary = fromASSText(secondary)
+	} else {
+		cue.Lines = fromASSText(text)
+	}
+
+	return cue, nil
+}
+
+// parseASSTime parses an H:MM:SS.cc timestamp into milliseconds.
+func parseASSTime(s string) (int64, error) {
+	return parseTimecode(strings.TrimSpace(s))
+}
@@ -0,0 +1,101 @@
+package subtitle
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/xifan2333/2sub/pkgs/asr"
+)
+
+var (
+	// assSecondaryTopPattern matches bilingual event text written with the
+	// secondary lines on top: "{\rSecondary}...\N{\r}...".
+	assSecondaryTopPattern = regexp.MustCompile(`^\{\\r([^}\\]+)\}(.*?)\\N\{\\r\}(.*)$`)
+
+	// assSecondaryBottomPattern matches bilingual event text written with the
+	// secondary lines below: "...\N{\rSecondary}...".
+	assSecondaryBottomPattern = regexp.MustCompile(`^(.*?)\\N\{\\r([^}\\]+)\}(.*)$`)
+)
+
+// NewBilingual builds a bilingual document from an ASR result and its
+// translations.
+//
+// Cues are created exactly as FromStandardResult does, so the original ASR
+// sentence boundaries and timings are preserved. The translation of each cue
+// becomes its primary text and the ASR text becomes the secondary text.
+// translations must contain one entry per cue, in order.
+//
+// Example:
+//
+//	doc := subtitle.FromStandardResult(result)
+//	translations := translate(doc) // one string per cue
+//	bilingual, err := subtitle.NewBilingual(result, "zh-CN", translations)
+func NewBilingual(result *asr.StandardResult, language string, translations []string) (*Document, error) {
+	doc := FromStandardResult(result)
+	if len(translations) != len(doc.Cues) {
+		return nil, &ValidationError{
+			Field:   "translations",
+			Message: fmt.Sprintf("got %d translations for %d cues", len(translations), len(doc.Cues)),
+		}
+	}
+
+	doc.SecondaryLanguage = doc.Language
+	doc.Language = language
+	for i := range doc.Cues {
+		cue := &doc.Cues[i]
+		cue.Secondary = cue.Lines
+		cue.SetText(translations[i])
+	}
+
+	return doc, nil
+}
+
+// stackLines returns the primary and secondary lines of a cue in display order.
+func stackLines(cue *Cue, secondaryOnTop bool) []string {
+	if !cue.IsBilingual() {
+		return cue.Lines
+	}
+
+	lines := make([]string, 0, len(cue.Lines)+len(cue.Secondary))
+	if secondaryOnTop {
+		lines = append(lines, cue.Secondary...)
+		return append(lines, cue.Lines...)
+	}
+	lines = append(lines, cue.Lines...)
+	return append(lines, cue.Secondary...)
+}
+
+// toASSBilingualText formats a bilingual cue as a single event whose
+// secondary lines switch to the secondary style with a \r override.
+// Keeping both tracks in one event lets the renderer stack them
+// regardless of how many lines each one has.
+func toASSBilingualText(cue *Cue, secondaryStyle string, secondaryOnTop bool) string {
+	primary := toASSText(cue.Lines)
+	secondary := toASSText(cue.Secondary)
+	if secondaryOnTop {
+		return fmt.Sprintf(`{\r%s}%s\N{\r}%s`, secondaryStyle, secondary, primary)
+	}
+	return fmt.Sprintf(`%s\N{\r%s}%s`, primary, secondaryStyle, secondary)
+}
+
+// splitASSBilingual splits bilingual event text written by
+// toASSBilingualText. It returns ok == false for monolingual text.
+func splitASSBilingual(text string) (primary, secondary, style string, secondaryOnTop, ok bool) {
+	if m := assSecondaryTopPattern.FindStringSubmatch(text); m != nil {
+		return m[3], m[2], m[1], true, true
+	}
+	if m := assSecondaryBottomPattern.FindStringSubmatch(text); m != nil {
+		return m[1], m[3], m[2], false, true
+	}
+	return "", "", "", false, false
+}
+
+// hasBilingualCues reports whether any cue in the document is bilingual.
+func hasBilingualCues(doc *Document) bool {
+	for i := range doc.Cues {
+		if doc.Cues[i].IsBilingual() {
+			return true
+		}
+	}
+	return false
+}
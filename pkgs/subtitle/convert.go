@@ -0,0 +1,182 @@
+package subtitle
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/xifan2333/2sub/pkgs/asr"
+)
+
+// wordPauseGap is the silence (in milliseconds) between two words that
+// starts a new cue when building cues from word timestamps.
+const wordPauseGap = 1000
+
+// FromStandardResult converts an ASR result into a subtitle document.
+//
+// One cue is created per sentence. Sentences without a speaker take the
+// speaker of the first word-level speaker ID within their time span.
+// Providers that do not return sentences (e.g., ElevenLabs) fall back to
+// Words: consecutive words are grouped into a cue until a sentence-ending
+// punctuation mark, a speaker change or a pause longer than one second.
+//
+// Cue text is kept on a single line; no line breaking is applied.
+func FromStandardResult(result *asr.StandardResult) *Document {
+	doc := NewDocument("")
+	if result == nil {
+		return doc
+	}
+	doc.Language = result.Language
+
+	if len(result.Sentences) > 0 {
+		for _, sentence := range result.Sentences {
+			text := strings.TrimSpace(sentence.Text)
+			if text == "" {
+				continue
+			}
+			speaker := sentence.SpeakerID
+			if speaker == "" {
+				speaker = speakerOf(result.Words, sentence.Start, sentence.End)
+			}
+			doc.Add(Cue{
+				Start:   sentence.Start,
+				End:     sentence.End,
+				Lines:   []string{text},
+				Speaker: speaker,
+			})
+		}
+		return doc
+	}
+
+	for _, group := range groupWords(result.Words) {
+		text := strings.TrimSpace(JoinWords(group))
+		if text == "" {
+			continue
+		}
+		doc.Add(Cue{
+			Start:   group[0].Start,
+			End:     group[len(group)-1].End,
+			Lines:   []string{text},
+			Speaker: group[0].SpeakerID,
+		})
+	}
+	return doc
+}
+
+// speakerOf returns the speaker ID of the first word with a speaker
+// between start and end, or "" if there is none.
+func speakerOf(words []asr.Word, start, end int64) string {
+	for _, word := range words {
+		if word.SpeakerID != "" && word.Start >= start && word.End <= end {
+			return word.SpeakerID
+		}
+	}
+	return ""
+}
+
+// groupWords splits words into sentence-like groups.
+// Whitespace-only words are attached to the current group but never
+// start or end one, so they do not affect cue timings.
+func groupWords(words []asr.Word) [][]asr.Word {
+	var groups [][]asr.Word
+	var current []asr.Word
+
+	flush := func() {
+		// Trim trailing whitespace words so the group ends on speech
+		for len(current) > 0 && strings.TrimSpace(current[len(current)-1].Text) == "" {
+			current = current[:len(current)-1]
+		}
+		if len(current) > 0 {
+			groups = append(groups, current)
+		}
+		current = nil
+	}
+
+	for _, word := range words {
+		if strings.TrimSpace(word.Text) == "" {
+			if len(current) > 0 {
+				current = append(current, word)
+			}
+			continue
+		}
+
+		if len(current) > 0 {
+			last := lastSpoken(current)
+			if word.SpeakerID != last.SpeakerID || word.Start-last.End > wordPauseGap {
+				flush()
+			}
+		}
+
+		current = append(current, word)
+
+		if EndsSentence(word.Text) {
+			flush()
+		}
+	}
+	flush()
+
+	return groups
+}
+
+// lastSpoken returns the last non-whitespace word of a group.
+func lastSpoken(words []asr.Word) asr.Word {
+	for i := len(words) - 1; i >= 0; i-- {
+		if strings.TrimSpace(words[i].Text) != "" {
+			return words[i]
+		}
+	}
+	return words[len(words)-1]
+}
+
+// EndsSentence reports whether text ends with sentence-ending punctuation,
+// ignoring inline markup and closing quotes.
+func EndsSentence(text string) bool {
+	text = strings.TrimRight(strings.TrimSpace(StripMarkup(text)), `"'”’」』`)
+	r, _ := utf8.DecodeLastRuneInString(text)
+	switch r {
+	case '.', '?', '!', '…', '。', '？', '！':
+		return true
+	}
+	return false
+}
+
+// JoinWords concatenates ASR words into display text.
+//
+// Providers differ in how they tokenize: ElevenLabs returns explicit
+// whitespace tokens, while JianYing and Bijian return bare phrases or
+// characters. A space is inserted only between two non-CJK words that
+// are not already separated, and never before punctuation.
+func JoinWords(words []asr.Word) string {
+	var b strings.Builder
+	for i, word := range words {
+		if i > 0 && needsSpace(words[i-1].Text, word.Text) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(word.Text)
+	}
+	return b.String()
+}
+
+// needsSpace reports whether a space must be inserted between prev and next.
+func needsSpace(prev, next string) bool {
+	if prev == "" || next == "" {
+		return false
+	}
+	last, _ := utf8.DecodeLastRuneInString(prev)
+	first, _ := utf8.DecodeRuneInString(next)
+	if unicode.IsSpace(last) || unicode.IsSpace(first) {
+		return false
+	}
+	if IsCJK(last) || IsCJK(first) {
+		return false
+	}
+	return unicode.IsLetter(first) || unicode.IsDigit(first)
+}
+
+// IsCJK reports whether r is a Chinese, Japanese or Korean character,
+// including CJK punctuation and full-width forms.
+func IsCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303F) || // CJK symbols and punctuation
+		(r >= 0xFF00 && r <= 0xFFEF) // half-width and full-width forms
+}
@@ -0,0 +1,137 @@
+// Package subtitle provides the canonical subtitle document model shared by the
+// translation workflow (txt → srt → srt → ass).
+//
+// ASR results are converted into a Document, which is then timed, reviewed and
+// finally written out in one of the supported subtitle formats. Every stage of
+// the workflow works on the same Document and Cue types, so tools never need
+// to define their own cue structures.
+//
+// Example usage:
+//
+//	import (
+//	    "github.com/xifan2333/2sub/pkgs/asr"
+//	    "github.com/xifan2333/2sub/pkgs/subtitle"
+//	)
+//
+//	result, err := asr.Transcribe(ctx, "jianying", "audio.mp3", nil)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	doc := subtitle.FromStandardResult(result)
+//	for _, cue := range doc.Cues {
+//	    fmt.Println(cue.Start, cue.End, cue.Text())
+//	}
+package subtitle
+
+import (
+	"sort"
+	"strings"
+)
+
+// Document represents a complete subtitle track.
+type Document struct {
+	// Language is the language code of the cue text (optional).
+	// The format follows the source it was built from (e.g., "zh-CN", "en").
+	Language string `json:"language,omitempty"`
+
+	// SecondaryLanguage is the language code of the secondary text track
+	// of bilingual documents (optional).
+	SecondaryLanguage string `json:"secondary_language,omitempty"`
+
+	// Cues contains the subtitle cues in display order.
+	Cues []Cue `json:"cues"`
+
+	// Metadata contains document-level key/value information such as
+	// the title or format-specific headers (optional).
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Cue represents a single subtitle event.
+//
+// All timestamps are in milliseconds since the start of the media.
+type Cue struct {
+	// Start is the start time in milliseconds.
+	Start int64 `json:"start"`
+
+	// End is the end time in milliseconds.
+	End int64 `json:"end"`
+
+	// Lines contains the displayed text, one entry per rendered line.
+	// Inline markup uses SRT-style tags (<i>, <b>, <u>).
+	Lines []string `json:"lines"`
+
+	// Secondary contains the lines of the secondary text track, such as the
+	// source-language text of a bilingual subtitle (optional).
+	Secondary []string `json:"secondary,omitempty"`
+
+	// Speaker identifies the speaker of the cue (optional).
+	Speaker string `json:"speaker,omitempty"`
+
+	// Style is the name of the style applied to the cue (optional).
+	// It is used by formats that support named styles, such as ASS.
+	Style string `json:"style,omitempty"`
+
+	// Metadata contains cue-level key/value information (optional).
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// NewDocument creates an empty document for the given language.
+func NewDocument(language string) *Document {
+	return &Document{
+		Language: language,
+		Cues:     make([]Cue, 0),
+	}
+}
+
+// Add appends a cue to the document.
+func (d *Document) Add(cue Cue) {
+	d.Cues = append(d.Cues, cue)
+}
+
+// Sort orders the cues by start time, then by end time.
+// The relative order of cues with identical timings is preserved.
+func (d *Document) Sort() {
+	sort.SliceStable(d.Cues, func(i, j int) bool {
+		if d.Cues[i].Start != d.Cues[j].Start {
+			return d.Cues[i].Start < d.Cues[j].Start
+		}
+		return d.Cues[i].End < d.Cues[j].End
+	})
+}
+
+// Duration returns the end time of the last cue in milliseconds.
+func (d *Document) Duration() int64 {
+	var end int64
+	for _, cue := range d.Cues {
+		if cue.End > end {
+			end = cue.End
+		}
+	}
+	return end
+}
+
+// Text returns the cue lines joined with newlines.
+func (c *Cue) Text() string {
+	return strings.Join(c.Lines, "\n")
+}
+
+// SetText replaces the cue lines by splitting text on newlines.
+func (c *Cue) SetText(text string) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	c.Lines = strings.Split(text, "\n")
+}
+
+// SecondaryText returns the secondary lines joined with newlines.
+func (c *Cue) SecondaryText() string {
+	return strings.Join(c.Secondary, "\n")
+}
+
+// IsBilingual reports whether the cue carries a secondary text track.
+func (c *Cue) IsBilingual() bool {
+	return len(c.Secondary) > 0
+}
+
+// Duration returns the display duration of the cue in milliseconds.
+func (c *Cue) Duration() int64 {
+	return c.End - c.Start
+}
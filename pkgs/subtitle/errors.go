@@ -0,0 +1,70 @@
+package subtitle
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationError represents a validation error for writer options.
+type ValidationError struct {
+	// Field is the name of the field that failed validation.
+	Field string
+
+	// Message describes what validation failed.
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("validation error on field '%s': %s", e.Field, e.Message)
+}
+
+// ParseError represents an error found while reading a subtitle file.
+type ParseError struct {
+	// Format is the subtitle format being read (e.g., "srt", "ass").
+	Format string
+
+	// Line is the 1-based line number where the error occurred.
+	// Zero means the error is not tied to a specific line.
+	Line int
+
+	// Message provides a human-readable description of the error.
+	Message string
+
+	// Err is the underlying error, if any.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s parse error", e.Format)
+	if e.Line > 0 {
+		fmt.Fprintf(&b, " at line %d", e.Line)
+	}
+	fmt.Fprintf(&b, ": %s", e.Message)
+	if e.Err != nil {
+		fmt.Fprintf(&b, ": %v", e.Err)
+	}
+	return b.String()
+}
+
+// Unwrap returns the underlying error for error chain inspection.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseErrors is a collection of parse errors reported in strict mode.
+type ParseErrors []*ParseError
+
+func (e ParseErrors) Error() string {
+	if len(e) == 0 {
+		return ""
+	}
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	msg := fmt.Sprintf("%d parse errors:", len(e))
+	for _, err := range e {
+		msg += "\n  - " + err.Error()
+	}
+	return msg
+}
@@ -0,0 +1,77 @@
+package subtitle
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// LanguageSpec contains the subtitle specification for a language,
+// as defined by the workflow's Netflix-based style guide.
+type LanguageSpec struct {
+	// Language is the normalized language code ("en", "zh", "ja", "ko").
+	Language string
+
+	// MaxCharsPerLine is the maximum number of characters per line.
+	MaxCharsPerLine int
+
+	// MaxLines is the maximum number of lines per cue.
+	MaxLines int
+
+	// CJK reports whether the language is Chinese, Japanese or Korean.
+	CJK bool
+
+	// AdultCPS is the maximum reading speed for adult programs,
+	// in characters per second.
+	AdultCPS float64
+
+	// ChildrenCPS is the maximum reading speed for children's programs,
+	// in characters per second.
+	ChildrenCPS float64
+}
+
+// languageSpecs holds the specification of each supported language.
+// Where the style guide gives a range, the upper bound is used.
+var languageSpecs = map[string]LanguageSpec{
+	"en": {Language: "en", MaxCharsPerLine: 42, MaxLines: 2, AdultCPS: 20, ChildrenCPS: 17},
+	"zh": {Language: "zh", MaxCharsPerLine: 18, MaxLines: 2, CJK: true, AdultCPS: 7, ChildrenCPS: 6},
+	"ja": {Language: "ja", MaxCharsPerLine: 18, MaxLines: 2, CJK: true, AdultCPS: 8, ChildrenCPS: 7},
+	"ko": {Language: "ko", MaxCharsPerLine: 20, MaxLines: 2, CJK: true, AdultCPS: 10, ChildrenCPS: 8},
+}
+
+// languageAliases maps ISO 639-2/3 and regional codes to the base language.
+var languageAliases = map[string]string{
+	"eng": "en",
+	"zho": "zh", "chi": "zh", "cmn": "zh", "yue": "zh",
+	"jpn": "ja",
+	"kor": "ko",
+}
+
+// NormalizeLanguage reduces a language code to its base language
+// (e.g., "zh-CN", "zho" and "cmn-Hans" all become "zh").
+func NormalizeLanguage(language string) string {
+	base := strings.ToLower(strings.TrimSpace(language))
+	if i := strings.IndexAny(base, "-_"); i >= 0 {
+		base = base[:i]
+	}
+	if alias, ok := languageAliases[base]; ok {
+		return alias
+	}
+	return base
+}
+
+// SpecFor returns the subtitle specification for a language.
+//
+// Unknown or empty languages use the English specification.
+func SpecFor(language string) LanguageSpec {
+	if spec, ok := languageSpecs[NormalizeLanguage(language)]; ok {
+		return spec
+	}
+	return languageSpecs["en"]
+}
+
+// CharCount returns the number of displayed characters in text,
+// ignoring inline markup and line breaks.
+func CharCount(text string) int {
+	text = StripMarkup(text)
+	return utf8.RuneCountInString(text) - strings.Count(text, "\n")
+}
@@ -0,0 +1,33 @@
+package linebreak
+
+import "fmt"
+
+// Rule identifiers reported by Violation.
+const (
+	// RuleLineLength means a single word or phrase is longer than a line.
+	RuleLineLength = "line-length"
+
+	// RuleMaxLines means the text does not fit in the maximum number of lines.
+	RuleMaxLines = "max-lines"
+
+	// RuleSplitUnit means the only break that fits splits a unit that should
+	// stay together (article and noun, first and last name, measure word and
+	// noun, ...).
+	RuleSplitUnit = "split-unit"
+)
+
+// Violation reports that no break compliant with the rules exists.
+//
+// Break still returns its best-effort lines alongside a Violation, so callers
+// can decide whether to accept them or flag the cue for review.
+type Violation struct {
+	// Rule identifies the rule that could not be satisfied.
+	Rule string
+
+	// Message provides a human-readable description of the violation.
+	Message string
+}
+
+func (e *Violation) Error() string {
+	return fmt.Sprintf("line break violation '%s': %s", e.Rule, e.Message)
+}
@@ -0,0 +1,463 @@
+// Package linebreak splits subtitle text into lines following the
+// Netflix-based rules of the subtitle workflow.
+//
+// A cue is kept on one line when it fits. Otherwise it is split into two lines,
+// preferring a bottom-heavy pyramid (the second line at least as long as the
+// first), breaks after punctuation and before conjunctions or prepositions.
+// Breaks that split units which belong together are avoided:
+//
+//   - English: article/determiner and noun, adjective and noun,
+//     first and last name, subject pronoun and verb
+//   - CJK: negation and verb, numeral and measure word, measure word and noun,
+//     and line-start/line-end punctuation (kinsoku)
+//
+// CJK text is broken at spaces when it has any, and between characters otherwise.
+//
+// Example:
+//
+//	lines, err := linebreak.Break("I told you that the old man was never coming back.", "en")
+//	var v *linebreak.Violation
+//	if errors.As(err, &v) {
+//	    // lines is a best-effort result; v.Rule says what could not be satisfied
+//	}
+package linebreak
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/xifan2333/2sub/pkgs/subtitle"
+)
+
+// Scoring weights for candidate break points. Lower scores are better.
+const (
+	// splitUnitPenalty is added to breaks that split a unit.
+	splitUnitPenalty = 1000
+
+	// midWordPenalty is added to breaks between characters of CJK text
+	// that has spaces to break at.
+	midWordPenalty = 40
+
+	// topHeavyPenalty is added per character the first line is longer than the second.
+	topHeavyPenalty = 3
+
+	// clauseBonus is subtracted from breaks after clause-ending punctuation.
+	clauseBonus = 30
+
+	// conjunctionBonus is subtracted from breaks before a conjunction or preposition.
+	conjunctionBonus = 10
+)
+
+// Rules contains the line limits used by Break.
+type Rules struct {
+	// MaxCharsPerLine is the maximum number of characters per line.
+	MaxCharsPerLine int
+
+	// MaxLines is the maximum number of lines.
+	MaxLines int
+
+	// CJK enables the Chinese, Japanese and Korean break rules.
+	CJK bool
+}
+
+// RulesFor returns the line rules for a language,
+// as defined by subtitle.SpecFor.
+func RulesFor(language string) Rules {
+	spec := subtitle.SpecFor(language)
+	return Rules{
+		MaxCharsPerLine: spec.MaxCharsPerLine,
+		MaxLines:        spec.MaxLines,
+		CJK:             spec.CJK,
+	}
+}
+
+// Validate validates the rules and sets default values.
+func (r *Rules) Validate() error {
+	if r.MaxCharsPerLine < 0 {
+		return &subtitle.ValidationError{Field: "MaxCharsPerLine", Message: "must not be negative"}
+	}
+	if r.MaxLines < 0 {
+		return &subtitle.ValidationError{Field: "MaxLines", Message: "must not be negative"}
+	}
+
+	if r.MaxCharsPerLine == 0 {
+		r.MaxCharsPerLine = 42
+	}
+	if r.MaxLines == 0 {
+		r.MaxLines = 2
+	}
+
+	return nil
+}
+
+// Break splits cue text into lines using the rules of the given language.
+//
+// See BreakWithRules.
+func Break(text, language string) ([]string, error) {
+	return BreakWithRules(text, RulesFor(language))
+}
+
+// BreakWithRules splits cue text into lines.
+//
+// Existing line breaks are discarded, except in dialogue cues where each line
+// starts with a dash; those keep one speaker per line. Inline markup is
+// preserved and does not count toward line length.
+//
+// When no compliant break exists, the best-effort lines are returned together
+// with a *Violation describing the broken rule.
+//
+// Returns an error if the rules are invalid.
+func BreakWithRules(text string, rules Rules) ([]string, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	if lines, ok := dialogueLines(text); ok {
+		return lines, checkLines(lines, rules)
+	}
+
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return nil, nil
+	}
+
+	if subtitle.CharCount(text) <= rules.MaxCharsPerLine {
+		return []string{text}, nil
+	}
+
+	if rules.MaxLines == 2 {
+		if lines, split, ok := bestPair(text, rules); ok {
+			if split {
+				return lines, &Violation{
+					Rule:    RuleSplitUnit,
+					Message: fmt.Sprintf("no break between %q and %q keeps units together", lines[0], lines[1]),
+				}
+			}
+			return lines, nil
+		}
+	}
+
+	lines := wrap(text, rules)
+	return lines, checkLines(lines, rules)
+}
+
+// dialogueLines returns the lines of a dialogue cue,
+// in which every line after the first starts with a dash.
+func dialogueLines(text string) ([]string, bool) {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) < 2 {
+		return nil, false
+	}
+
+	for _, line := range lines[1:] {
+		plain := subtitle.StripMarkup(line)
+		if !strings.HasPrefix(plain, "-") && !strings.HasPrefix(plain, "–") {
+			return nil, false
+		}
+	}
+	return lines, true
+}
+
+// checkLines returns a Violation if lines exceed the line limits.
+func checkLines(lines []string, rules Rules) error {
+	for _, line := range lines {
+		if n := subtitle.CharCount(line); n > rules.MaxCharsPerLine {
+			return &Violation{
+				Rule:    RuleLineLength,
+				Message: fmt.Sprintf("line %q has %d characters, maximum is %d", line, n, rules.MaxCharsPerLine),
+			}
+		}
+	}
+	if len(lines) > rules.MaxLines {
+		return &Violation{
+			Rule:    RuleMaxLines,
+			Message: fmt.Sprintf("text needs %d lines, maximum is %d", len(lines), rules.MaxLines),
+		}
+	}
+	return nil
+}
+
+// candidate is a possible break point in a text.
+type candidate struct {
+	top    string
+	bottom string
+	score  int
+	split  bool
+}
+
+// bestPair returns the best two-line split of text whose lines both fit.
+// split reports whether the chosen break splits a unit.
+func bestPair(text string, rules Rules) (lines []string, split bool, ok bool) {
+	var best *candidate
+	for _, c := range candidates(text, rules) {
+		if subtitle.CharCount(c.top) > rules.MaxCharsPerLine || subtitle.CharCount(c.bottom) > rules.MaxCharsPerLine {
+			continue
+		}
+
+		c.score += shapePenalty(c.top, c.bottom)
+		if best == nil || c.score < best.score {
+			best = &c
+		}
+	}
+	if best == nil {
+		return nil, false, false
+	}
+	return []string{best.top, best.bottom}, best.split, true
+}
+
+// shapePenalty favours balanced, bottom-heavy line pairs.
+func shapePenalty(top, bottom string) int {
+	diff := subtitle.CharCount(top) - subtitle.CharCount(bottom)
+	if diff > 0 {
+		return diff * topHeavyPenalty
+	}
+	return -diff
+}
+
+// wrap fills lines greedily, taking the longest first line that fits at each
+// step and avoiding breaks that split units where possible.
+func wrap(text string, rules Rules) []string {
+	var lines []string
+	for subtitle.CharCount(text) > rules.MaxCharsPerLine {
+		var best, overflow *candidate
+		for _, c := range candidates(text, rules) {
+			if subtitle.CharCount(c.top) > rules.MaxCharsPerLine {
+				if overflow == nil {
+					overflow = &c
+				}
+				continue
+			}
+			if best == nil || (best.split && !c.split) ||
+				(best.split == c.split && subtitle.CharCount(c.top) >= subtitle.CharCount(best.top)) {
+				best = &c
+			}
+		}
+
+		if best == nil {
+			best = overflow
+		}
+		if best == nil {
+			break
+		}
+		lines = append(lines, best.top)
+		text = best.bottom
+	}
+	return append(lines, text)
+}
+
+// candidates returns every break point of text with its unit score.
+func candidates(text string, rules Rules) []candidate {
+	runes := []rune(text)
+	inTag := tagMask(runes)
+	hasSpace := strings.Contains(text, " ")
+
+	var result []candidate
+	for i := 1; i < len(runes); i++ {
+		if !tagBoundary(runes, inTag, i) {
+			continue
+		}
+
+		var top, bottom string
+		atSpace := runes[i] == ' '
+		switch {
+		case atSpace:
+			top, bottom = string(runes[:i]), string(runes[i+1:])
+		case rules.CJK && runes[i-1] != ' ' && cjkBoundary(runes, inTag, i):
+			top, bottom = string(runes[:i]), string(runes[i:])
+		default:
+			continue
+		}
+
+		top, bottom = strings.TrimSpace(top), strings.TrimSpace(bottom)
+		if strings.TrimSpace(subtitle.StripMarkup(top)) == "" || strings.TrimSpace(subtitle.StripMarkup(bottom)) == "" {
+			continue
+		}
+
+		c := candidate{top: top, bottom: bottom}
+		if rules.CJK {
+			c.score, c.split = scoreCJK(c.top, c.bottom)
+			if !atSpace && hasSpace {
+				c.score += midWordPenalty
+			}
+		} else {
+			c.score, c.split = scoreEnglish(c.top, c.bottom)
+		}
+		result = append(result, c)
+	}
+	return result
+}
+
+// tagMask marks the runes that belong to a markup tag, i.e. the
+// <...> spans that subtitle.StripMarkup removes entirely.
+func tagMask(runes []rune) []bool {
+	mask := make([]bool, len(runes))
+	for i, r := range runes {
+		if r != '<' {
+			continue
+		}
+		for j := i + 1; j < len(runes); j++ {
+			if runes[j] == '<' {
+				break
+			}
+			if runes[j] == '>' {
+				if subtitle.StripMarkup(string(runes[i:j+1])) == "" {
+					for k := i; k <= j; k++ {
+						mask[k] = true
+					}
+				}
+				break
+			}
+		}
+	}
+	return mask
+}
+
+// tagBoundary reports whether a break between runes i-1 and i keeps markup
+// intact: it must not fall inside a tag, after an opening tag or before a
+// closing tag.
+func tagBoundary(runes []rune, inTag []bool, i int) bool {
+	switch {
+	case !inTag[i-1] && !inTag[i]:
+		return true
+	case inTag[i-1] && inTag[i]:
+		return false
+	case inTag[i]:
+		// A tag starts at i
+		return runes[i+1] != '/'
+	default:
+		// A tag ends at i-1
+		j := i - 1
+		for j > 0 && runes[j] != '<' {
+			j--
+		}
+		return runes[j+1] == '/'
+	}
+}
+
+// cjkBoundary reports whether a break between runes i-1 and i
+// falls next to a CJK character.
+func cjkBoundary(runes []rune, inTag []bool, i int) bool {
+	prev, next := visibleBefore(runes, inTag, i), visibleAfter(runes, inTag, i)
+	return subtitle.IsCJK(prev) || subtitle.IsCJK(next) || isCJKPunct(prev) || isCJKPunct(next)
+}
+
+// visibleBefore returns the last rune before index i that is not markup.
+func visibleBefore(runes []rune, inTag []bool, i int) rune {
+	for j := i - 1; j >= 0; j-- {
+		if !inTag[j] {
+			return runes[j]
+		}
+	}
+	return 0
+}
+
+// visibleAfter returns the first rune at or after index i that is not markup.
+func visibleAfter(runes []rune, inTag []bool, i int) rune {
+	for j := i; j < len(runes); j++ {
+		if !inTag[j] {
+			return runes[j]
+		}
+	}
+	return 0
+}
+
+// isCJKPunct reports whether r is CJK or full-width punctuation.
+func isCJKPunct(r rune) bool {
+	return (r >= 0x3000 && r <= 0x303F) || (r >= 0xFF00 && r <= 0xFFEF) || r == '…' || r == '—'
+}
+
+// scoreEnglish scores a break between two lines of space-separated text.
+func scoreEnglish(top, bottom string) (int, bool) {
+	topWords := strings.Fields(subtitle.StripMarkup(top))
+	bottomWords := strings.Fields(subtitle.StripMarkup(bottom))
+	prev, next := topWords[len(topWords)-1], bottomWords[0]
+
+	if endsClause(prev) {
+		return -clauseBonus, false
+	}
+
+	prevWord, nextWord := bareWord(prev), bareWord(next)
+	prevLower, nextLower := strings.ToLower(prevWord), strings.ToLower(nextWord)
+
+	switch {
+	case determiners[prevLower]:
+		return splitUnitPenalty, true
+	case subjectPronouns[prevLower] && isLower(nextWord) && !breakBefore[nextLower]:
+		return splitUnitPenalty, true
+	case isAdjective(prevLower) && isLower(nextWord) && !breakBefore[nextLower] && !determiners[nextLower]:
+		return splitUnitPenalty, true
+	case isName(prevWord) && isName(nextWord) && !isFunctionWord(prevLower):
+		return splitUnitPenalty, true
+	}
+
+	if breakBefore[nextLower] {
+		return -conjunctionBonus, false
+	}
+	return 0, false
+}
+
+// scoreCJK scores a break between two lines of CJK text.
+func scoreCJK(top, bottom string) (int, bool) {
+	before := []rune(subtitle.StripMarkup(top))
+	after := []rune(subtitle.StripMarkup(bottom))
+	prev, next := before[len(before)-1], after[0]
+	var prev2 rune
+	if len(before) > 1 {
+		prev2 = before[len(before)-2]
+	}
+
+	switch {
+	case noLineStart[next] || noLineEnd[prev]:
+		return splitUnitPenalty, true
+	case negations[prev] && subtitle.IsCJK(next):
+		return splitUnitPenalty, true
+	case prev == '有' && prev2 == '没' && subtitle.IsCJK(next):
+		return splitUnitPenalty, true
+	case numerals[prev] && measureWords[next]:
+		return splitUnitPenalty, true
+	case measureWords[prev] && numerals[prev2] && subtitle.IsCJK(next):
+		return splitUnitPenalty, true
+	case clauseEnd[prev]:
+		return -clauseBonus, false
+	}
+	return 0, false
+}
+
+// endsClause reports whether a word ends with clause-ending punctuation.
+func endsClause(word string) bool {
+	r, _ := utf8.DecodeLastRuneInString(strings.TrimRight(word, `"'”’)`))
+	return clauseEnd[r]
+}
+
+// bareWord strips surrounding punctuation from a word.
+func bareWord(word string) string {
+	return strings.TrimFunc(word, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+}
+
+// isLower reports whether a word starts with a lower-case letter.
+func isLower(word string) bool {
+	r, _ := utf8.DecodeRuneInString(word)
+	return unicode.IsLower(r)
+}
+
+// isName reports whether a word looks like part of a proper name.
+func isName(word string) bool {
+	r, _ := utf8.DecodeRuneInString(word)
+	return unicode.IsUpper(r) && word != "I"
+}
+
+// isFunctionWord reports whether a lower-cased word is a determiner,
+// pronoun, conjunction or preposition, which are capitalized at the start
+// of a sentence without being names.
+func isFunctionWord(word string) bool {
+	return determiners[word] || subjectPronouns[word] || breakBefore[word]
+}
@@ -0,0 +1,79 @@
+package linebreak
+
+import "strings"
+
+// English word classes used to score break points.
+var (
+	// determiners must stay with the noun that follows them.
+	determiners = wordSet("a an the this these those my your his her its our their no every each some any")
+
+	// subjectPronouns must stay with the verb that follows them.
+	subjectPronouns = wordSet("i you he she we they it")
+
+	// breakBefore are conjunctions and prepositions; a line may start with them.
+	breakBefore = wordSet("and but or nor so yet because although though while when where if unless until " +
+		"that which who whom whose to of in on at for with from by about into onto over under " +
+		"after before between through during without within against")
+
+	// adjectives is a list of common adjectives that must stay with the noun
+	// that follows them. Suffix rules in isAdjective cover the rest.
+	adjectives = wordSet("big small little good bad new old young long short great high low large " +
+		"best worst better worse last next first other same whole real right wrong hard easy " +
+		"hot cold nice fine dark bright full empty free poor rich sure true own only open " +
+		"red blue green white black yellow brown")
+
+	// adjectiveSuffixes mark words that are most likely adjectives.
+	adjectiveSuffixes = []string{"ful", "ous", "ive", "less", "able", "ible", "ical", "ish"}
+)
+
+// CJK character classes used to score break points.
+var (
+	// negations must stay with the verb that follows them.
+	negations = runeSet("不没别未勿莫非甭")
+
+	// numerals precede measure words.
+	numerals = runeSet("一二两三四五六七八九十百千万亿零几每某这那哪半0123456789")
+
+	// measureWords must stay with both the numeral before and the noun after them.
+	measureWords = runeSet("个只条张本件位种次把头辆杯碗块片双对群家所台部篇首座棵朵根支枝颗粒份场顿间层匹封架艘道句段名岁")
+
+	// noLineStart cannot start a line (closing punctuation and small kana).
+	noLineStart = runeSet("，。、；：？！…—）」』】》〉”’.,;:?!)ぁぃぅぇぉっゃゅょゎァィゥェォッャュョヮー々")
+
+	// noLineEnd cannot end a line (opening punctuation).
+	noLineEnd = runeSet("（「『【《〈“‘(")
+
+	// clauseEnd marks punctuation after which a break is preferred.
+	clauseEnd = runeSet("，。、；：？！…—」』,.;:?!")
+)
+
+// wordSet builds a lookup set from space-separated words.
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+// runeSet builds a lookup set from the runes of s.
+func runeSet(s string) map[rune]bool {
+	set := make(map[rune]bool)
+	for _, r := range s {
+		set[r] = true
+	}
+	return set
+}
+
+// isAdjective reports whether a lower-cased word is likely an adjective.
+func isAdjective(word string) bool {
+	if adjectives[word] {
+		return true
+	}
+	for _, suffix := range adjectiveSuffixes {
+		if len(word) > len(suffix)+2 && strings.HasSuffix(word, suffix) {
+			return true
+		}
+	}
+	return false
+}
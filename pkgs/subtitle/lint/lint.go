@@ -0,0 +1,213 @@
+// Package lint checks subtitle documents against the quality checklist of the
+// subtitle workflow.
+//
+// Each check is a rule with a stable ID. Lint returns one Finding per problem
+// with the cue index, rule ID and severity. Findings that can be corrected
+// without changing the meaning of the text carry a Fix with the replacement
+// lines; ApplyFixes applies them.
+//
+// Rules:
+//
+//	chars-per-line   error    line longer than the language limit (fix: re-break)
+//	max-lines        error    more lines than allowed (fix: re-break)
+//	single-line      info     two lines that fit on one (fix: join)
+//	ellipsis         warning  "..." instead of U+2026 (fix: …)
+//	cjk-punctuation  warning  ，or 。 in CJK text (fix: half-width space)
+//	cjk-quotes       warning  Western quotes in CJK text (fix: 「」『』)
+//	numbers          warning  digits 0-10 in English text (fix: spell out)
+//	reading-speed    warning  cue shorter than its reading time
+//
+// Only the primary lines of bilingual cues are checked.
+//
+// Example:
+//
+//	findings, err := lint.Lint(doc, nil)
+//	if err != nil {
+//	    return err
+//	}
+//	for _, f := range findings {
+//	    fmt.Println(f)
+//	}
+//	applied, err := lint.ApplyFixes(doc, nil)
+package lint
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/xifan2333/2sub/pkgs/subtitle"
+)
+
+// Rule IDs.
+const (
+	RuleCharsPerLine   = "chars-per-line"
+	RuleMaxLines       = "max-lines"
+	RuleSingleLine     = "single-line"
+	RuleEllipsis       = "ellipsis"
+	RuleCJKPunctuation = "cjk-punctuation"
+	RuleCJKQuotes      = "cjk-quotes"
+	RuleNumbers        = "numbers"
+	RuleReadingSpeed   = "reading-speed"
+)
+
+// Severity is the severity of a finding.
+type Severity string
+
+const (
+	// SeverityError marks a violation of a hard limit of the specification.
+	SeverityError Severity = "error"
+
+	// SeverityWarning marks a violation of the house style.
+	SeverityWarning Severity = "warning"
+
+	// SeverityInfo marks a recommendation.
+	SeverityInfo Severity = "info"
+)
+
+// Finding is a problem found in a cue.
+type Finding struct {
+	// Index is the index of the cue in the document.
+	Index int `json:"index"`
+
+	// Rule is the ID of the rule that produced the finding.
+	Rule string `json:"rule"`
+
+	// Severity is the severity of the finding.
+	Severity Severity `json:"severity"`
+
+	// Message provides a human-readable description of the problem.
+	Message string `json:"message"`
+
+	// Fix is the safe automatic correction, or nil if the finding needs
+	// a human decision.
+	Fix *Fix `json:"fix,omitempty"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("cue %d: %s [%s]: %s", f.Index, f.Severity, f.Rule, f.Message)
+}
+
+// Fix is an automatic correction of a finding.
+type Fix struct {
+	// Lines replaces the primary lines of the cue.
+	Lines []string `json:"lines"`
+}
+
+// rule is a single check of the linter.
+type rule struct {
+	id    string
+	check func(l *linter, cue *subtitle.Cue) []Finding
+}
+
+// rules lists all checks in the order they run.
+var rules = []rule{
+	{RuleCharsPerLine, checkCharsPerLine},
+	{RuleMaxLines, checkMaxLines},
+	{RuleSingleLine, checkSingleLine},
+	{RuleEllipsis, checkEllipsis},
+	{RuleCJKPunctuation, checkCJKPunctuation},
+	{RuleCJKQuotes, checkCJKQuotes},
+	{RuleNumbers, checkNumbers},
+	{RuleReadingSpeed, checkReadingSpeed},
+}
+
+// linter holds the validated options of a Lint call.
+type linter struct {
+	opts *Options
+	cjk  bool
+}
+
+// Lint checks every cue of doc and returns the findings,
+// ordered by cue index and rule.
+//
+// If opts is nil, default options for the document language are used.
+//
+// Returns an error if doc is nil or the options are invalid.
+func Lint(doc *subtitle.Document, opts *Options) ([]Finding, error) {
+	l, err := newLinter(doc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for i := range doc.Cues {
+		findings = append(findings, l.lintCue(i, &doc.Cues[i])...)
+	}
+	return findings, nil
+}
+
+// ApplyFixes applies all safe fixes to doc in place and returns the number
+// of fixes applied.
+//
+// Fixes of the same cue are applied one at a time, re-checking the cue after
+// each, so that later fixes see the corrected text.
+//
+// Returns an error if doc is nil or the options are invalid.
+func ApplyFixes(doc *subtitle.Document, opts *Options) (int, error) {
+	l, err := newLinter(doc, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for i := range doc.Cues {
+		cue := &doc.Cues[i]
+		// Each fix resolves its finding, so a cue needs at most one
+		// round per rule
+		for range rules {
+			fix := firstFix(l.lintCue(i, cue))
+			if fix == nil {
+				break
+			}
+			cue.Lines = fix.Lines
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+// newLinter validates the options and creates a linter for doc.
+func newLinter(doc *subtitle.Document, opts *Options) (*linter, error) {
+	if doc == nil {
+		return nil, &subtitle.ValidationError{Field: "doc", Message: "is required"}
+	}
+
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Language == "" {
+		o.Language = doc.Language
+	}
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &linter{opts: &o, cjk: subtitle.SpecFor(o.Language).CJK}, nil
+}
+
+// lintCue runs all enabled rules on a cue.
+func (l *linter) lintCue(index int, cue *subtitle.Cue) []Finding {
+	var findings []Finding
+	for _, r := range rules {
+		if slices.Contains(l.opts.Disable, r.id) {
+			continue
+		}
+		for _, f := range r.check(l, cue) {
+			f.Index = index
+			f.Rule = r.id
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// firstFix returns the fix of the first fixable finding.
+func firstFix(findings []Finding) *Fix {
+	for _, f := range findings {
+		if f.Fix != nil {
+			return f.Fix
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package lint
+
+import (
+	"github.com/xifan2333/2sub/pkgs/subtitle"
+	"github.com/xifan2333/2sub/pkgs/subtitle/timing"
+)
+
+// Options contains options for linting a subtitle document.
+type Options struct {
+	// Language selects the language specification.
+	// If empty, the document language is used.
+	Language string
+
+	// MaxCharsPerLine is the maximum number of characters per line.
+	// Default: language specification (42 for English, 18 for Chinese)
+	MaxCharsPerLine int
+
+	// MaxLines is the maximum number of lines per cue.
+	// Default: language specification (2)
+	MaxLines int
+
+	// Profile selects the adult or children's reading speed.
+	// Default: timing.ProfileAdult
+	Profile timing.Profile
+
+	// CPS overrides the reading speed in characters per second.
+	// Default: reading speed of the language and profile
+	CPS float64
+
+	// Disable lists rule IDs that are not checked.
+	Disable []string
+}
+
+// Validate validates the options and sets default values.
+func (o *Options) Validate() error {
+	if o.MaxCharsPerLine < 0 {
+		return &subtitle.ValidationError{Field: "MaxCharsPerLine", Message: "must not be negative"}
+	}
+	if o.MaxLines < 0 {
+		return &subtitle.ValidationError{Field: "MaxLines", Message: "must not be negative"}
+	}
+
+	spec := subtitle.SpecFor(o.Language)
+	if o.MaxCharsPerLine == 0 {
+		o.MaxCharsPerLine = spec.MaxCharsPerLine
+	}
+	if o.MaxLines == 0 {
+		o.MaxLines = spec.MaxLines
+	}
+
+	// Reuse the timing defaults for the profile and reading speed
+	t := timing.Options{Language: o.Language, Profile: o.Profile, CPS: o.CPS}
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	o.Profile, o.CPS = t.Profile, t.CPS
+
+	return nil
+}
@@ -0,0 +1,292 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/xifan2333/2sub/pkgs/subtitle"
+	"github.com/xifan2333/2sub/pkgs/subtitle/linebreak"
+	"github.com/xifan2333/2sub/pkgs/subtitle/timing"
+)
+
+var (
+	// ellipsisPattern matches ellipses typed as repeated full stops.
+	ellipsisPattern = regexp.MustCompile(`\.{3,}|。{3,}|．{3,}`)
+
+	// numberPattern matches numbers with their decimal, time and unit marks,
+	// so only bare integers are spelled out.
+	numberPattern = regexp.MustCompile(`[$€£#]?\d+(?:[.,:/]\d+)*%?`)
+
+	// cjkStopPattern matches CJK commas and full stops with surrounding spaces.
+	cjkStopPattern = regexp.MustCompile(`\s*[，。]+\s*`)
+
+	// bracketSpacePattern matches spaces inside CJK brackets and quotes,
+	// left behind when a comma or full stop next to them is replaced.
+	bracketSpacePattern = regexp.MustCompile(`([「『“‘（【《])\s+|\s+([」』”’）】》])`)
+)
+
+// numberWords spells out the numbers 0 to 10.
+var numberWords = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten"}
+
+// checkCharsPerLine reports lines longer than MaxCharsPerLine.
+func checkCharsPerLine(l *linter, cue *subtitle.Cue) []Finding {
+	var findings []Finding
+	for i, line := range cue.Lines {
+		if n := subtitle.CharCount(line); n > l.opts.MaxCharsPerLine {
+			findings = append(findings, Finding{
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("line %d has %d characters, maximum is %d", i+1, n, l.opts.MaxCharsPerLine),
+				Fix:      l.rebreak(cue),
+			})
+		}
+	}
+	return findings
+}
+
+// checkMaxLines reports cues with more than MaxLines lines.
+func checkMaxLines(l *linter, cue *subtitle.Cue) []Finding {
+	if len(cue.Lines) <= l.opts.MaxLines {
+		return nil
+	}
+	return []Finding{{
+		Severity: SeverityError,
+		Message:  fmt.Sprintf("cue has %d lines, maximum is %d", len(cue.Lines), l.opts.MaxLines),
+		Fix:      l.rebreak(cue),
+	}}
+}
+
+// checkSingleLine reports multi-line cues whose text fits on one line.
+func checkSingleLine(l *linter, cue *subtitle.Cue) []Finding {
+	if len(cue.Lines) < 2 {
+		return nil
+	}
+	fix := l.rebreak(cue)
+	if fix == nil || len(fix.Lines) != 1 {
+		return nil
+	}
+	return []Finding{{
+		Severity: SeverityInfo,
+		Message:  "cue fits on a single line",
+		Fix:      fix,
+	}}
+}
+
+// checkCJKPunctuation reports CJK commas and full stops,
+// which the house style replaces with a half-width space.
+func checkCJKPunctuation(l *linter, cue *subtitle.Cue) []Finding {
+	if !l.cjk || !strings.ContainsAny(cue.Text(), "，。") {
+		return nil
+	}
+
+	lines := make([]string, len(cue.Lines))
+	for i, line := range cue.Lines {
+		line = cjkStopPattern.ReplaceAllString(line, " ")
+		lines[i] = strings.TrimSpace(bracketSpacePattern.ReplaceAllString(line, "$1$2"))
+	}
+	return []Finding{{
+		Severity: SeverityWarning,
+		Message:  "CJK text uses ，or 。; use a half-width space instead",
+		Fix:      &Fix{Lines: lines},
+	}}
+}
+
+// checkCJKQuotes reports Western quotation marks in CJK text.
+func checkCJKQuotes(l *linter, cue *subtitle.Cue) []Finding {
+	if !l.cjk || !strings.ContainsAny(cue.Text(), "\"“”‘’") {
+		return nil
+	}
+
+	f := Finding{
+		Severity: SeverityWarning,
+		Message:  "CJK text uses Western quotation marks; use 「」 and 『』 instead",
+	}
+	if lines, ok := cornerQuotes(cue.Lines); ok {
+		// Only apostrophes, such as in "don’t"
+		if slices.Equal(lines, cue.Lines) {
+			return nil
+		}
+		f.Fix = &Fix{Lines: lines}
+	}
+	return []Finding{f}
+}
+
+// checkEllipsis reports ellipses typed as repeated full stops.
+func checkEllipsis(l *linter, cue *subtitle.Cue) []Finding {
+	if !ellipsisPattern.MatchString(cue.Text()) {
+		return nil
+	}
+
+	lines := make([]string, len(cue.Lines))
+	for i, line := range cue.Lines {
+		lines[i] = ellipsisPattern.ReplaceAllString(line, "…")
+	}
+	return []Finding{{
+		Severity: SeverityWarning,
+		Message:  "ellipsis typed as full stops; use … (U+2026)",
+		Fix:      &Fix{Lines: lines},
+	}}
+}
+
+// checkNumbers reports digits 0 to 10 in English text,
+// which are spelled out.
+func checkNumbers(l *linter, cue *subtitle.Cue) []Finding {
+	if subtitle.NormalizeLanguage(l.opts.Language) != "en" {
+		return nil
+	}
+
+	var findings []Finding
+	lines := make([]string, len(cue.Lines))
+	for i, line := range cue.Lines {
+		sentenceStart := i == 0 || subtitle.EndsSentence(cue.Lines[i-1])
+
+		var b strings.Builder
+		last := 0
+		for _, loc := range numberPattern.FindAllStringIndex(line, -1) {
+			token := line[loc[0]:loc[1]]
+			n, err := strconv.Atoi(token)
+			if err != nil || n > 10 || !bareNumber(line, loc[0], loc[1]) {
+				continue
+			}
+			word := numberWords[n]
+			prefix := strings.TrimLeft(strings.TrimSpace(subtitle.StripMarkup(line[:loc[0]])), "-–")
+			if (prefix == "" && sentenceStart) || (prefix != "" && subtitle.EndsSentence(prefix)) {
+				word = strings.ToUpper(word[:1]) + word[1:]
+			}
+			b.WriteString(line[last:loc[0]])
+			b.WriteString(word)
+			last = loc[1]
+		}
+		b.WriteString(line[last:])
+		lines[i] = b.String()
+	}
+
+	for i, line := range lines {
+		if line != cue.Lines[i] {
+			findings = append(findings, Finding{
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("line %d uses digits for a number from 0 to 10; spell it out", i+1),
+				Fix:      &Fix{Lines: lines},
+			})
+		}
+	}
+	return findings
+}
+
+// checkReadingSpeed reports cues that are too short to be read.
+func checkReadingSpeed(l *linter, cue *subtitle.Cue) []Finding {
+	required := timing.RequiredDuration(cue.Text(), l.opts.CPS)
+	duration := cue.Duration()
+	if duration >= required {
+		return nil
+	}
+
+	cps := float64(subtitle.CharCount(cue.Text()))
+	if duration > 0 {
+		cps = cps * 1000 / float64(duration)
+	}
+	return []Finding{{
+		Severity: SeverityWarning,
+		Message: fmt.Sprintf("reading speed is %.1f characters per second, maximum is %g (needs %d ms, has %d ms)",
+			cps, l.opts.CPS, required, duration),
+	}}
+}
+
+// rebreak returns a fix that re-breaks the cue text,
+// or nil if no compliant break exists.
+func (l *linter) rebreak(cue *subtitle.Cue) *Fix {
+	lines, err := linebreak.BreakWithRules(cue.Text(), linebreak.Rules{
+		MaxCharsPerLine: l.opts.MaxCharsPerLine,
+		MaxLines:        l.opts.MaxLines,
+		CJK:             l.cjk,
+	})
+	if err != nil || len(lines) == 0 {
+		return nil
+	}
+	return &Fix{Lines: lines}
+}
+
+// bareNumber reports whether the number at line[start:end] stands alone,
+// not attached to letters (e.g., "3D", "9th").
+func bareNumber(line string, start, end int) bool {
+	if start > 0 {
+		r, size := utf8.DecodeLastRuneInString(line[:start])
+		if unicode.IsLetter(r) {
+			return false
+		}
+		if r == '-' {
+			if prev, _ := utf8.DecodeLastRuneInString(line[:start-size]); unicode.IsDigit(prev) {
+				return false
+			}
+		}
+	}
+	if end < len(line) {
+		r, _ := utf8.DecodeRuneInString(line[end:])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// cornerQuotes converts Western quotation marks to corner brackets:
+// 「」 for outer quotes and 『』 for nested quotes. Straight double quotes
+// alternate between opening and closing. Single quotation marks after a
+// non-CJK letter are apostrophes and are kept.
+//
+// ok is false if the quotes are unbalanced.
+func cornerQuotes(lines []string) ([]string, bool) {
+	var stack []rune
+	open := func(src rune) rune {
+		depth := len(stack)
+		stack = append(stack, src)
+		if depth%2 == 0 {
+			return '「'
+		}
+		return '『'
+	}
+	closeQuote := func() (rune, bool) {
+		if len(stack) == 0 {
+			return 0, false
+		}
+		stack = stack[:len(stack)-1]
+		if len(stack)%2 == 0 {
+			return '」', true
+		}
+		return '』', true
+	}
+
+	result := make([]string, len(lines))
+	for i, line := range lines {
+		var b strings.Builder
+		var last rune
+		for _, r := range line {
+			switch {
+			case r == '“' || (r == '‘' && !isApostrophe(last)) ||
+				(r == '"' && (len(stack) == 0 || stack[len(stack)-1] != '"')):
+				b.WriteRune(open(r))
+			case r == '”' || (r == '’' && !isApostrophe(last)) || r == '"':
+				c, ok := closeQuote()
+				if !ok {
+					return nil, false
+				}
+				b.WriteRune(c)
+			default:
+				b.WriteRune(r)
+			}
+			last = r
+		}
+		result[i] = b.String()
+	}
+	return result, len(stack) == 0
+}
+
+// isApostrophe reports whether a single quotation mark following last is an
+// apostrophe (e.g., "don’t"), not a quote.
+func isApostrophe(last rune) bool {
+	return unicode.IsLetter(last) && !subtitle.IsCJK(last)
+}
@@ -0,0 +1,70 @@
+package subtitle
+
+import (
+	"strings"
+)
+
+// textRun is a piece of cue text with uniform inline formatting.
+type textRun struct {
+	Text      string
+	Italic    bool
+	Bold      bool
+	Underline bool
+	Strike    bool
+}
+
+// markupState tracks the inline formatting that is active while
+// scanning cue lines. Formatting carries over from one line to the next.
+type markupState struct {
+	italic, bold, underline, strike bool
+}
+
+// parseMarkup splits a line into runs of uniformly formatted text using
+// the SRT-style tags <i>, <b>, <u> and <s>. Unknown tags are kept as text
+// and unbalanced closing tags are ignored, so the result is always well formed.
+func parseMarkup(line string, state *markupState) []textRun {
+	var runs []textRun
+	var b strings.Builder
+
+	flush := func() {
+		if b.Len() > 0 {
+			runs = append(runs, textRun{
+				Text:      b.String(),
+				Italic:    state.italic,
+				Bold:      state.bold,
+				Underline: state.underline,
+				Strike:    state.strike,
+			})
+			b.Reset()
+		}
+	}
+
+	last := 0
+	for _, loc := range markupTagPattern.FindAllStringIndex(line, -1) {
+		b.WriteString(line[last:loc[0]])
+		last = loc[1]
+
+		tag := strings.ToLower(line[loc[0]:loc[1]])
+		open := !strings.HasPrefix(tag, "</")
+		flush()
+		switch strings.Trim(tag, "</>") {
+		case "i":
+			state.italic = open
+		case "b":
+			state.bold = open
+		case "u":
+			state.underline = open
+		case "s":
+			state.strike = open
+		}
+	}
+	b.WriteString(line[last:])
+	flush()
+
+	return runs
+}
+
+// StripMarkup removes inline formatting tags (<i>, <b>, <u>, <s>) from text.
+func StripMarkup(text string) string {
+	return markupTagPattern.ReplaceAllString(text, "")
+}
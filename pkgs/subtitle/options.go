@@ -0,0 +1,159 @@
+package subtitle
+
+import "fmt"
+
+// ReadOptions contains options shared by all subtitle readers.
+type ReadOptions struct {
+	// Strict rejects any deviation from the format specification.
+	//
+	// In strict mode every problem is reported as a line-numbered
+	// ParseError and the read fails. In lenient mode (the default) the
+	// reader recovers from common real-world damage such as missing
+	// indexes, wrong separators or irregular blank lines, and only fails
+	// when no cue can be recovered at all.
+	Strict bool
+}
+
+// SRTOptions contains options for writing SubRip (.srt) files.
+type SRTOptions struct {
+	// CRLF writes Windows line endings instead of "\n".
+	// Default: false
+	CRLF bool
+
+	// BOM writes a UTF-8 byte order mark at the start of the file.
+	// Some legacy players need it to detect the encoding.
+	// Default: false
+	BOM bool
+
+	// SecondaryOnTop writes the secondary lines of bilingual cues above the
+	// primary lines instead of below them.
+	// Default: false
+	SecondaryOnTop bool
+}
+
+// ASSOptions contains options for writing Advanced SubStation Alpha (.ass) files.
+//
+// Styles are authored at StyleResY and scaled to PlayResY when written, so the
+// same style sheet produces correctly sized 1080p and 4K variants:
+//
+//	hd := &subtitle.ASSOptions{PlayResX: 1920, PlayResY: 1080}
+//	uhd := &subtitle.ASSOptions{PlayResX: 3840, PlayResY: 2160}
+type ASSOptions struct {
+	// Title is written as the script title (optional).
+	Title string
+
+	// PlayResX is the horizontal script resolution.
+	// Default: 1920
+	PlayResX int
+
+	// PlayResY is the vertical script resolution.
+	// Default: 1080
+	PlayResY int
+
+	// StyleResY is the vertical resolution the styles were designed for.
+	// Font sizes, outlines, shadows and margins are multiplied by
+	// PlayResY/StyleResY when written.
+	// Default: 1080
+	StyleResY int
+
+	// Styles is the style sheet written to the [V4+ Styles] section.
+	// Cues reference styles by name; cues without a style use the first one.
+	// Default: a single DefaultStyle()
+	Styles []Style
+
+	// ScriptInfo contains additional [Script Info] headers (optional).
+	ScriptInfo map[string]string
+
+	// SecondaryStyle is the style used for the secondary lines of bilingual
+	// cues. If no style with this name is in Styles, DefaultSecondaryStyle()
+	// is added under this name when the document has bilingual cues.
+	// Default: "Secondary"
+	SecondaryStyle string
+
+	// SecondaryOnTop places the secondary lines of bilingual cues above the
+	// primary lines instead of below them.
+	// Default: false
+	SecondaryOnTop bool
+}
+
+// Validate validates the options and sets default values.
+//
+// Default values:
+//   - PlayResX: 1920, PlayResY: 1080
+//   - StyleResY: 1080
+//   - Styles: a single DefaultStyle()
+//   - SecondaryStyle: "Secondary"
+//
+// Returns an error if a resolution is negative or a style has no name.
+func (o *ASSOptions) Validate() error {
+	if o.PlayResX == 0 {
+		o.PlayResX = 1920
+	}
+	if o.PlayResY == 0 {
+		o.PlayResY = 1080
+	}
+	if o.StyleResY == 0 {
+		o.StyleResY = 1080
+	}
+	if len(o.Styles) == 0 {
+		o.Styles = []Style{DefaultStyle()}
+	}
+	if o.SecondaryStyle == "" {
+		o.SecondaryStyle = "Secondary"
+	}
+
+	if o.PlayResX < 0 || o.PlayResY < 0 || o.StyleResY < 0 {
+		return &ValidationError{Field: "PlayResX/PlayResY/StyleResY", Message: "must be positive"}
+	}
+
+	for i, style := range o.Styles {
+		if style.Name == "" {
+			return &ValidationError{Field: fmt.Sprintf("Styles[%d].Name", i), Message: "style name is required"}
+		}
+	}
+
+	return nil
+}
+
+// VTTOptions contains options for writing WebVTT (.vtt) files.
+type VTTOptions struct {
+	// Settings are the default cue settings appended to every timing line
+	// (e.g., "align:center line:90%"). A cue can override them with the
+	// MetaVTTSettings metadata key.
+	// Default: no settings
+	Settings string
+
+	// DisableVoices omits the <v Speaker> voice span that is otherwise
+	// written for cues with a speaker.
+	// Default: false
+	DisableVoices bool
+
+	// SecondaryOnTop writes the secondary lines of bilingual cues above the
+	// primary lines instead of below them.
+	// Default: false
+	SecondaryOnTop bool
+}
+
+// TTMLOptions contains options for writing TTML documents conforming to
+// the IMSC1 text profile.
+type TTMLOptions struct {
+	// Title is written as the document title (optional).
+	Title string
+
+	// Language is the xml:lang of the document.
+	// Default: the document language, or "und" if unknown
+	Language string
+
+	// SecondaryOnTop writes the secondary lines of bilingual cues above the
+	// primary lines instead of below them.
+	// Default: false
+	SecondaryOnTop bool
+}
+
+// SBVOptions contains options for writing YouTube SubViewer (.sbv) files.
+type SBVOptions struct {
+	// SecondaryOnTop writes the secondary lines of bilingual cues above the
+	// primary lines instead of below them.
+	// Default: false
+	SecondaryOnTop bool
+}
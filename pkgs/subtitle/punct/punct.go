@@ -0,0 +1,315 @@
+// Package punct normalizes Chinese, Japanese and Korean punctuation to the
+// subtitle house style of the workflow.
+//
+// The house style for CJK subtitles is:
+//   - no commas or full stops: ，。 (and , . after CJK characters) become a
+//     single half-width space, or disappear at the end of a line
+//   - restrained ？！: repeated marks are collapsed to one, and half-width
+//     marks after CJK characters become full-width
+//   - ellipses are written … and dashes ——
+//   - quotes are corner brackets: 「」 for outer quotes and 『』 for nested ones
+//
+// ASR providers such as JianYing and Bijian return text full of ，。"".
+// NormalizeResult rewrites an ASR result word by word, so word timings stay
+// aligned with the rewritten text; NormalizeDocument rewrites subtitle cues.
+// Both leave text in a reported non-CJK language untouched.
+//
+// Example:
+//
+//	punct.Normalize(`他说，"我不去。"`) // 他说 「我不去」
+//
+//	result = punct.NormalizeResult(result)
+//	doc := subtitle.FromStandardResult(result)
+package punct
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/xifan2333/2sub/pkgs/asr"
+	"github.com/xifan2333/2sub/pkgs/subtitle"
+)
+
+// ellipsisPattern matches ellipses typed as repeated dots or repeated ….
+var ellipsisPattern = regexp.MustCompile(`\.{2,}|。{2,}|．{2,}|…+`)
+
+// Normalize applies the CJK punctuation house style to text.
+func Normalize(text string) string {
+	n := &normalizer{}
+	return strings.TrimSpace(n.rewrite(text))
+}
+
+// NormalizeLines applies the CJK punctuation house style to the lines of a
+// cue. Quotes may span lines. Lines that become empty are removed.
+func NormalizeLines(lines []string) []string {
+	n := &normalizer{}
+	var result []string
+	for _, line := range lines {
+		if line = strings.TrimSpace(n.rewrite(line)); line != "" {
+			result = append(result, line)
+		}
+		n.last = 0
+	}
+	return result
+}
+
+// NormalizeWords applies the CJK punctuation house style to a sequence of
+// ASR words.
+//
+// Words are rewritten one by one with shared state, so quotes are matched
+// across words. No word is added or removed and timings are unchanged;
+// a word that only contained punctuation may become blank.
+func NormalizeWords(words []asr.Word) []asr.Word {
+	n := &normalizer{}
+	result := make([]asr.Word, len(words))
+	for i, word := range words {
+		word.Text = n.rewrite(word.Text)
+		result[i] = word
+	}
+	trimWordSpaces(result)
+	return result
+}
+
+// NormalizeResult returns a copy of an ASR result with the CJK punctuation
+// house style applied to its text, words and sentences.
+//
+// Results that report a language other than Chinese, Japanese or Korean
+// are copied unchanged. Results without a language, such as those of
+// Bijian, are normalized. Returns nil if result is nil.
+func NormalizeResult(result *asr.StandardResult) *asr.StandardResult {
+	if result == nil {
+		return nil
+	}
+
+	normalized := *result
+	if result.Language != "" && !subtitle.SpecFor(result.Language).CJK {
+		return &normalized
+	}
+	normalized.Text = Normalize(result.Text)
+	normalized.Words = NormalizeWords(result.Words)
+	if result.Sentences != nil {
+		normalized.Sentences = make([]asr.Sentence, len(result.Sentences))
+		for i, sentence := range result.Sentences {
+			sentence.Text = Normalize(sentence.Text)
+			normalized.Sentences[i] = sentence
+		}
+	}
+	return &normalized
+}
+
+// NormalizeDocument applies the CJK punctuation house style to the cues of
+// doc in place.
+//
+// Primary lines are rewritten if the document language is Chinese, Japanese
+// or Korean, and secondary lines if the secondary language is.
+func NormalizeDocument(doc *subtitle.Document) {
+	if doc == nil {
+		return
+	}
+
+	primary := subtitle.SpecFor(doc.Language).CJK
+	secondary := doc.SecondaryLanguage != "" && subtitle.SpecFor(doc.SecondaryLanguage).CJK
+	for i := range doc.Cues {
+		cue := &doc.Cues[i]
+		if primary {
+			cue.Lines = NormalizeLines(cue.Lines)
+		}
+		if secondary {
+			cue.Secondary = NormalizeLines(cue.Secondary)
+		}
+	}
+}
+
+// normalizer rewrites text rune by rune. It keeps state between calls so
+// that text split into several pieces (words, lines) is handled as a whole.
+type normalizer struct {
+	// quotes is the stack of open quotation marks, as they appeared in the input.
+	quotes []rune
+
+	// last is the last rune written, or 0 at the start of a line.
+	last rune
+}
+
+// rewrite normalizes s, continuing from the state of previous calls.
+func (n *normalizer) rewrite(s string) string {
+	runes := []rune(ellipsisPattern.ReplaceAllString(s, "…"))
+
+	var out []rune
+	emit := func(r rune) {
+		out = append(out, r)
+		n.last = r
+	}
+	space := func() {
+		if n.last != 0 && n.last != ' ' && !isOpening(n.last) {
+			emit(' ')
+		}
+	}
+	trimSpace := func() {
+		if len(out) > 0 && out[len(out)-1] == ' ' {
+			out = out[:len(out)-1]
+		}
+	}
+
+	for i, r := range runes {
+		var next rune
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch {
+		case r == '，' || r == '。' || r == '．':
+			space()
+		case (r == ',' || r == '.') && isCJKLetter(n.last) && !unicode.IsDigit(next):
+			space()
+		case r == '　' || unicode.IsSpace(r):
+			space()
+
+		case r == '？' || r == '！' || ((r == '?' || r == '!') && isCJKLetter(n.last)):
+			if n.last == '？' || n.last == '！' {
+				continue
+			}
+			trimSpace()
+			emit(toFullWidth(r))
+
+		case r == '…':
+			if n.last == '…' {
+				continue
+			}
+			trimSpace()
+			emit('…')
+
+		case r == '—' || r == '―':
+			if n.last == '—' {
+				continue
+			}
+			emit('—')
+			emit('—')
+
+		case r == '「' || r == '『':
+			n.quotes = append(n.quotes, r)
+			emit(r)
+		case r == '」' || r == '』':
+			n.pop()
+			trimSpace()
+			emit(r)
+
+		case r == '“' || (r == '‘' && !isApostrophe(n.last)) ||
+			(r == '"' && (len(n.quotes) == 0 || n.quotes[len(n.quotes)-1] != '"')):
+			emit(n.open(r))
+		case r == '”' || (r == '’' && !isApostrophe(n.last)) || r == '"':
+			trimSpace()
+			emit(n.closeQuote())
+
+		default:
+			if isClosing(r) {
+				trimSpace()
+			}
+			emit(r)
+		}
+	}
+
+	return string(out)
+}
+
+// open pushes a quotation mark and returns the corner bracket for its depth.
+func (n *normalizer) open(r rune) rune {
+	depth := len(n.quotes)
+	n.quotes = append(n.quotes, r)
+	if depth%2 == 0 {
+		return '「'
+	}
+	return '『'
+}
+
+// closeQuote pops a quotation mark and returns the corner bracket for its depth.
+func (n *normalizer) closeQuote() rune {
+	n.pop()
+	if len(n.quotes)%2 == 0 {
+		return '」'
+	}
+	return '』'
+}
+
+// pop removes the innermost open quotation mark, if any.
+func (n *normalizer) pop() {
+	if len(n.quotes) > 0 {
+		n.quotes = n.quotes[:len(n.quotes)-1]
+	}
+}
+
+// trimWordSpaces removes the spaces left at the edges of the text and
+// duplicate spaces between words.
+func trimWordSpaces(words []asr.Word) {
+	first := true
+	for i := range words {
+		if words[i].Text == "" {
+			continue
+		}
+		if first {
+			words[i].Text = strings.TrimLeft(words[i].Text, " ")
+			if words[i].Text == "" {
+				continue
+			}
+			first = false
+		}
+
+		if !strings.HasSuffix(words[i].Text, " ") {
+			continue
+		}
+		next := nextText(words, i+1)
+		if next == "" || strings.HasPrefix(next, " ") || startsClosing(next) {
+			words[i].Text = strings.TrimRight(words[i].Text, " ")
+		}
+	}
+}
+
+// nextText returns the text of the first non-empty word at or after i.
+func nextText(words []asr.Word, i int) string {
+	for ; i < len(words); i++ {
+		if words[i].Text != "" {
+			return words[i].Text
+		}
+	}
+	return ""
+}
+
+// startsClosing reports whether text starts with closing punctuation.
+func startsClosing(text string) bool {
+	for _, r := range text {
+		return isClosing(r)
+	}
+	return false
+}
+
+// isCJKLetter reports whether r is a CJK character other than punctuation.
+func isCJKLetter(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// isApostrophe reports whether a single quotation mark following last is an
+// apostrophe (e.g., "don’t"), not a quote.
+func isApostrophe(last rune) bool {
+	return unicode.IsLetter(last) && !isCJKLetter(last)
+}
+
+// isOpening reports whether r is an opening bracket or quote.
+func isOpening(r rune) bool {
+	return strings.ContainsRune("「『（【《〈", r)
+}
+
+// isClosing reports whether r is punctuation that must not follow a space.
+func isClosing(r rune) bool {
+	return strings.ContainsRune("」』）】》〉？！…、；：", r)
+}
+
+// toFullWidth converts ? and ! to their full-width forms.
+func toFullWidth(r rune) rune {
+	switch r {
+	case '?':
+		return '？'
+	case '!':
+		return '！'
+	}
+	return r
+}
@@ -0,0 +1,40 @@
+package subtitle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// WriteSBV writes a document in YouTube SubViewer (.sbv) format.
+//
+// SBV has no markup or speaker support, so inline tags are removed and
+// speakers are not written. If opts is nil, default options are used.
+func WriteSBV(w io.Writer, doc *Document, opts *SBVOptions) error {
+	if opts == nil {
+		opts = &SBVOptions{}
+	}
+
+	bw := bufio.NewWriter(w)
+	for i := range doc.Cues {
+		cue := &doc.Cues[i]
+		if i > 0 {
+			bw.WriteString("\n")
+		}
+		fmt.Fprintf(bw, "%s,%s\n", formatSBVTime(cue.Start), formatSBVTime(cue.End))
+		for _, line := range stackLines(cue, opts.SecondaryOnTop) {
+			bw.WriteString(StripMarkup(line))
+			bw.WriteString("\n")
+		}
+	}
+
+	return bw.Flush()
+}
+
+// formatSBVTime formats milliseconds as H:MM:SS.mmm.
+func formatSBVTime(ms int64) string {
+	if ms < 0 {
+		ms = 0
+	}
+	return fmt.Sprintf("%d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
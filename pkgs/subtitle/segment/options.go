@@ -0,0 +1,77 @@
+package segment
+
+import "github.com/xifan2333/2sub/pkgs/subtitle"
+
+// Options contains options for segmenting ASR words into cues.
+//
+// Zero values take the defaults of the language specification
+// returned by subtitle.SpecFor.
+type Options struct {
+	// Language selects the subtitle specification. If empty, the language
+	// of the ASR result is used.
+	Language string
+
+	// MaxCharsPerLine is the maximum number of characters per line.
+	// Default: language specification (42 for English, 18 for Chinese)
+	MaxCharsPerLine int
+
+	// MaxLines is the maximum number of lines per cue.
+	// Default: language specification (2)
+	MaxLines int
+
+	// MaxDuration is the maximum duration of a cue in milliseconds.
+	// Default: 7000
+	MaxDuration int64
+
+	// MaxCPS is the maximum reading speed in characters per second.
+	// Default: the adult reading speed of the language specification
+	// (20 for English, 7 for Chinese)
+	MaxCPS float64
+
+	// PauseGap is the silence in milliseconds between two words that always
+	// ends a cue.
+	// Default: 1000
+	PauseGap int64
+
+	// IgnoreSpeakers keeps words of different speakers in the same cue.
+	// Default: false
+	IgnoreSpeakers bool
+}
+
+// Validate validates the options and sets default values.
+func (o *Options) Validate() error {
+	if o.MaxCharsPerLine < 0 {
+		return &subtitle.ValidationError{Field: "MaxCharsPerLine", Message: "must not be negative"}
+	}
+	if o.MaxLines < 0 {
+		return &subtitle.ValidationError{Field: "MaxLines", Message: "must not be negative"}
+	}
+	if o.MaxDuration < 0 {
+		return &subtitle.ValidationError{Field: "MaxDuration", Message: "must not be negative"}
+	}
+	if o.MaxCPS < 0 {
+		return &subtitle.ValidationError{Field: "MaxCPS", Message: "must not be negative"}
+	}
+	if o.PauseGap < 0 {
+		return &subtitle.ValidationError{Field: "PauseGap", Message: "must not be negative"}
+	}
+
+	spec := subtitle.SpecFor(o.Language)
+	if o.MaxCharsPerLine == 0 {
+		o.MaxCharsPerLine = spec.MaxCharsPerLine
+	}
+	if o.MaxLines == 0 {
+		o.MaxLines = spec.MaxLines
+	}
+	if o.MaxDuration == 0 {
+		o.MaxDuration = 7000
+	}
+	if o.MaxCPS == 0 {
+		o.MaxCPS = spec.AdultCPS
+	}
+	if o.PauseGap == 0 {
+		o.PauseGap = 1000
+	}
+
+	return nil
+}
@@ -0,0 +1,316 @@
+// Package segment re-segments ASR word timings into subtitle-sized cues.
+//
+// Provider sentences are often too long for a single cue, and some providers
+// (e.g., ElevenLabs) return no sentences at all. Segment walks the word list
+// instead and groups words into cues that respect the line limits, maximum
+// duration and reading speed of the target language.
+//
+// Words are first split at speaker changes and pauses longer than PauseGap.
+// Groups that are still too long are split recursively at the best word
+// boundary, preferring sentence ends, then clause punctuation, then the
+// longest pause, then balanced halves. Cue start and end times are always
+// taken from word timestamps, so sync stays accurate.
+//
+// Reading speed is a soft limit: cue timings cannot be stretched beyond the
+// words they contain, so groups that read too fast are split only where each
+// part becomes readable in the time until the next cue, split points that keep
+// each cue readable are preferred, and cues that remain too fast are left for
+// timing adjustment.
+//
+// Example:
+//
+//	result, _ := asr.Transcribe(ctx, "elevenlabs", "audio.mp3", nil)
+//	doc, err := segment.Segment(result, &segment.Options{Language: "en"})
+//	if err != nil {
+//	    return err
+//	}
+//	subtitle.WriteSRT(w, doc, nil)
+package segment
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xifan2333/2sub/pkgs/asr"
+	"github.com/xifan2333/2sub/pkgs/subtitle"
+	"github.com/xifan2333/2sub/pkgs/subtitle/linebreak"
+)
+
+// Split point scores. Lower scores are better.
+const (
+	// sentenceBonus is subtracted from split points after a sentence end.
+	sentenceBonus = 100
+
+	// clauseBonus is subtracted from split points after clause punctuation.
+	clauseBonus = 50
+
+	// maxPauseBonus caps the bonus for splitting at a pause
+	// (one point per 10 ms of silence).
+	maxPauseBonus = 100
+
+	// fitBonus is subtracted from split points whose halves both fit in a cue.
+	fitBonus = 50
+
+	// unreadablePenalty is added for each half that exceeds the reading speed.
+	unreadablePenalty = 200
+)
+
+// Segment groups the words of an ASR result into subtitle cues.
+//
+// Each cue's text is broken into lines with the linebreak package. The speaker
+// of a cue is the speaker of its first word. Sentences are ignored; results
+// without words produce an empty document.
+//
+// If opts is nil, default options for the result language are used.
+//
+// Returns an error if result is nil or the options are invalid.
+func Segment(result *asr.StandardResult, opts *Options) (*subtitle.Document, error) {
+	if result == nil {
+		return nil, &subtitle.ValidationError{Field: "result", Message: "is required"}
+	}
+
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Language == "" {
+		o.Language = result.Language
+	}
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &segmenter{
+		opts: &o,
+		rules: linebreak.Rules{
+			MaxCharsPerLine: o.MaxCharsPerLine,
+			MaxLines:        o.MaxLines,
+			CJK:             subtitle.SpecFor(o.Language).CJK,
+		},
+		layouts: make(map[span]bool),
+	}
+
+	doc := subtitle.NewDocument(o.Language)
+	groups := s.splitHard(result.Words)
+	for i, group := range groups {
+		next := int64(-1)
+		if i+1 < len(groups) {
+			next = groups[i+1][0].Start
+		}
+
+		for _, words := range s.split(group, next) {
+			doc.Add(subtitle.Cue{
+				Start:   words[0].Start,
+				End:     words[len(words)-1].End,
+				Lines:   s.lines(words),
+				Speaker: words[0].SpeakerID,
+			})
+		}
+	}
+
+	return doc, nil
+}
+
+// segmenter holds the validated options of a Segment call.
+type segmenter struct {
+	opts  *Options
+	rules linebreak.Rules
+
+	// layouts caches the result of fitsLayout for each range of words.
+	layouts map[span]bool
+}
+
+// span identifies a range of words by its first word and length.
+// Ranges are always subslices of the groups returned by splitHard.
+type span struct {
+	first *asr.Word
+	n     int
+}
+
+// splitHard splits words at speaker changes and long pauses.
+// Whitespace-only words never start or end a group.
+func (s *segmenter) splitHard(words []asr.Word) [][]asr.Word {
+	var groups [][]asr.Word
+	var current []asr.Word
+
+	flush := func() {
+		if current = trim(current); len(current) > 0 {
+			groups = append(groups, current)
+		}
+		current = nil
+	}
+
+	for _, word := range words {
+		if isBlank(word) {
+			if len(current) > 0 {
+				current = append(current, word)
+			}
+			continue
+		}
+
+		if len(current) > 0 {
+			last := trim(current)
+			prev := last[len(last)-1]
+			if (!s.opts.IgnoreSpeakers && word.SpeakerID != prev.SpeakerID) || word.Start-prev.End > s.opts.PauseGap {
+				flush()
+			}
+		}
+		current = append(current, word)
+	}
+	flush()
+
+	return groups
+}
+
+// split recursively splits words until every part fits in a cue.
+// next is the start of the following speech, or -1 if there is none.
+//
+// Words that fit the layout but read too fast are only split if both
+// parts become readable; otherwise they are kept for timing adjustment.
+func (s *segmenter) split(words []asr.Word, next int64) [][]asr.Word {
+	if s.fits(words, next) {
+		return [][]asr.Word{words}
+	}
+	tooFast := s.fitsLayout(words)
+
+	var bestLeft, bestRight []asr.Word
+	bestScore := 0
+	for k := 1; k < len(words); k++ {
+		if isBlank(words[k]) {
+			continue
+		}
+		left, right := trim(words[:k]), trim(words[k:])
+		if len(left) == 0 || len(right) == 0 {
+			continue
+		}
+		if tooFast && (!s.readable(left, right[0].Start) || !s.readable(right, next)) {
+			continue
+		}
+
+		score := s.score(left, right, next)
+		if bestLeft == nil || score < bestScore {
+			bestLeft, bestRight, bestScore = left, right, score
+		}
+	}
+
+	// A single word, or words that no split makes readable, are kept as is
+	if bestLeft == nil {
+		return [][]asr.Word{words}
+	}
+
+	return append(s.split(bestLeft, bestRight[0].Start), s.split(bestRight, next)...)
+}
+
+// score rates splitting a group into left and right.
+func (s *segmenter) score(left, right []asr.Word, next int64) int {
+	leftChars, rightChars := subtitle.CharCount(text(left)), subtitle.CharCount(text(right))
+	score := 0
+	if total := leftChars + rightChars; total > 0 {
+		diff := leftChars - rightChars
+		if diff < 0 {
+			diff = -diff
+		}
+		score = diff * 100 / total
+	}
+
+	r, _ := utf8.DecodeLastRuneInString(strings.TrimSpace(left[len(left)-1].Text))
+	switch {
+	case strings.ContainsRune(".?!…。？！", r):
+		score -= sentenceBonus
+	case strings.ContainsRune(",;:，、；：—", r):
+		score -= clauseBonus
+	}
+
+	pause := (right[0].Start - left[len(left)-1].End) / 10
+	if pause > maxPauseBonus {
+		pause = maxPauseBonus
+	}
+	if pause > 0 {
+		score -= int(pause)
+	}
+
+	if s.fitsLayout(left) && s.fitsLayout(right) {
+		score -= fitBonus
+	}
+	if !s.readable(left, right[0].Start) {
+		score += unreadablePenalty
+	}
+	if !s.readable(right, next) {
+		score += unreadablePenalty
+	}
+
+	return score
+}
+
+// fits reports whether words fit in a single cue and can be read in the
+// time until next.
+func (s *segmenter) fits(words []asr.Word, next int64) bool {
+	return s.fitsLayout(words) && s.readable(words, next)
+}
+
+// fitsLayout reports whether words fit in the lines and maximum duration
+// of a single cue. Results are cached, as split checks the same ranges
+// at each level of recursion.
+func (s *segmenter) fitsLayout(words []asr.Word) bool {
+	if words[len(words)-1].End-words[0].Start > s.opts.MaxDuration {
+		return false
+	}
+
+	key := span{first: &words[0], n: len(words)}
+	if ok, cached := s.layouts[key]; cached {
+		return ok
+	}
+
+	_, err := linebreak.BreakWithRules(text(words), s.rules)
+	var v *linebreak.Violation
+	ok := err == nil || (errors.As(err, &v) && v.Rule == linebreak.RuleSplitUnit)
+	s.layouts[key] = ok
+	return ok
+}
+
+// readable reports whether words can be read at MaxCPS in the time between
+// their start and the next speech (or their end if next is -1),
+// capped at MaxDuration.
+func (s *segmenter) readable(words []asr.Word, next int64) bool {
+	start, end := words[0].Start, words[len(words)-1].End
+	if next > end {
+		end = next
+	}
+	window := end - start
+	if window > s.opts.MaxDuration {
+		window = s.opts.MaxDuration
+	}
+	return float64(subtitle.CharCount(text(words))) <= s.opts.MaxCPS*float64(window)/1000
+}
+
+// lines returns the text of words broken into lines.
+// Lines that violate the rules are kept as a best effort.
+func (s *segmenter) lines(words []asr.Word) []string {
+	lines, _ := linebreak.BreakWithRules(text(words), s.rules)
+	if len(lines) == 0 {
+		return []string{text(words)}
+	}
+	return lines
+}
+
+// text returns the display text of words.
+func text(words []asr.Word) string {
+	return strings.TrimSpace(subtitle.JoinWords(words))
+}
+
+// trim removes leading and trailing whitespace-only words.
+func trim(words []asr.Word) []asr.Word {
+	for len(words) > 0 && isBlank(words[0]) {
+		words = words[1:]
+	}
+	for len(words) > 0 && isBlank(words[len(words)-1]) {
+		words = words[:len(words)-1]
+	}
+	return words
+}
+
+// isBlank reports whether a word contains only whitespace.
+func isBlank(word asr.Word) bool {
+	return strings.TrimSpace(word.Text) == ""
+}
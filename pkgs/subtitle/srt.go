@@ -0,0 +1,233 @@
+package subtitle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// srtTimingPattern matches a lenient SRT timing line, capturing both
+	// timestamps and ignoring trailing position coordinates.
+	srtTimingPattern = regexp.MustCompile(`^(\S+)\s*-{1,2}>\s*(\S+)(?:\s+.*)?$`)
+
+	// srtStrictTimingPattern matches a timing line as defined by the SubRip format.
+	srtStrictTimingPattern = regexp.MustCompile(`^\d{2}:\d{2}:\d{2},\d{3} --> \d{2}:\d{2}:\d{2},\d{3}(?: .*)?$`)
+
+	// srtIndexPattern matches a cue index line.
+	srtIndexPattern = regexp.MustCompile(`^\d+$`)
+)
+
+// ReadSRT reads a SubRip (.srt) document.
+//
+// The reader accepts UTF-8 input with or without a byte order mark and
+// with either LF or CRLF line endings. In lenient mode it also recovers from:
+//   - Missing or out-of-sequence cue indexes
+//   - Period or colon instead of comma as the millisecond separator
+//   - Missing, doubled or extra blank lines between cues
+//   - Stray blank lines inside cue text
+//
+// In strict mode each deviation is reported as a line-numbered ParseError.
+// If opts is nil, lenient mode is used.
+func ReadSRT(r io.Reader, opts *ReadOptions) (*Document, error) {
+	if opts == nil {
+		opts = &ReadOptions{}
+	}
+
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, &ParseError{Format: "srt", Message: "failed to read input", Err: err}
+	}
+
+	doc := NewDocument("")
+	var issues ParseErrors
+	report := func(line int, format string, args ...interface{}) {
+		issues = append(issues, &ParseError{Format: "srt", Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var (
+		cur       *Cue // cue currently receiving text, nil when skipping
+		curLine   int  // line number of the current cue's timing line
+		lastIndex int  // index of the previous cue
+		sawBlank  bool // a blank line was seen since the last text line
+	)
+
+	finish := func() {
+		if cur == nil {
+			return
+		}
+		if len(cur.Lines) == 0 {
+			report(curLine, "cue has no text")
+		} else {
+			doc.Add(*cur)
+		}
+		cur = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		trimmed := strings.TrimSpace(lines[i])
+
+		if trimmed == "" {
+			sawBlank = true
+			continue
+		}
+
+		// Determine whether this line starts a new cue, either with an
+		// index followed by a timing line or with a bare timing line.
+		timingLine := -1
+		hasIndex := false
+		if srtIndexPattern.MatchString(trimmed) && i+1 < len(lines) && isSRTTiming(lines[i+1]) {
+			timingLine = i + 1
+			hasIndex = true
+		} else if isSRTTiming(trimmed) {
+			timingLine = i
+		}
+
+		if timingLine < 0 {
+			if cur == nil {
+				report(lineNo, "unexpected text outside of a cue: %q", trimmed)
+				continue
+			}
+			if sawBlank && len(cur.Lines) > 0 {
+				report(lineNo, "blank line inside cue text")
+			}
+			cur.Lines = append(cur.Lines, strings.TrimRight(lines[i], " \t"))
+			sawBlank = false
+			continue
+		}
+
+		if cur != nil && !sawBlank {
+			report(lineNo, "missing blank line before cue")
+		}
+		finish()
+
+		index := lastIndex + 1
+		if hasIndex {
+			n, _ := strconv.Atoi(trimmed)
+			if n != lastIndex+1 {
+				report(lineNo, "cue index %d out of sequence, expected %d", n, lastIndex+1)
+			}
+			index = n
+		} else {
+			report(lineNo, "missing cue index")
+		}
+		lastIndex = index
+
+		i = timingLine
+		lineNo = i + 1
+		timing := strings.TrimSpace(lines[i])
+		if !srtStrictTimingPattern.MatchString(timing) {
+			report(lineNo, "non-standard timing line %q", timing)
+		}
+
+		start, end, err := parseSRTTiming(timing)
+		if err != nil {
+			issues = append(issues, &ParseError{Format: "srt", Line: lineNo, Message: "invalid timing line", Err: err})
+			cur = nil
+			sawBlank = false
+			continue
+		}
+		if end < start {
+			report(lineNo, "cue ends before it starts")
+		}
+
+		cur = &Cue{Start: start, End: end, Lines: make([]string, 0, 2)}
+		curLine = lineNo
+		sawBlank = false
+	}
+	finish()
+
+	if opts.Strict && len(issues) > 0 {
+		return nil, issues
+	}
+
+	if len(doc.Cues) == 0 && len(issues) > 0 {
+		return nil, &ParseError{Format: "srt", Message: "no valid cues found", Err: issues}
+	}
+
+	return doc, nil
+}
+
+// isSRTTiming reports whether line looks like an SRT timing line.
+func isSRTTiming(line string) bool {
+	m := srtTimingPattern.FindStringSubmatch(strings.TrimSpace(line))
+	return m != nil && timecodePattern.MatchString(m[1])
+}
+
+// parseSRTTiming parses the start and end timestamps of a timing line.
+func parseSRTTiming(line string) (int64, int64, error) {
+	m := srtTimingPattern.FindStringSubmatch(line)
+	if m == nil {
+		return 0, 0, fmt.Errorf("missing '-->' separator")
+	}
+
+	start, err := parseTimecode(m[1])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	end, err := parseTimecode(m[2])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return start, end, nil
+}
+
+// WriteSRT writes a document in SubRip (.srt) format.
+//
+// Cues are numbered from 1 in document order. Timestamps are written with
+// millisecond precision, so reading the output back yields identical timings.
+// Bilingual cues are written as the primary lines followed by the secondary
+// lines, or the other way round when opts.SecondaryOnTop is set.
+// If opts is nil, LF line endings without a byte order mark are used.
+func WriteSRT(w io.Writer, doc *Document, opts *SRTOptions) error {
+	if opts == nil {
+		opts = &SRTOptions{}
+	}
+
+	newline := "\n"
+	if opts.CRLF {
+		newline = "\r\n"
+	}
+
+	bw := bufio.NewWriter(w)
+	if opts.BOM {
+		bw.WriteString(utf8BOM)
+	}
+
+	for i, cue := range doc.Cues {
+		if i > 0 {
+			bw.WriteString(newline)
+		}
+		fmt.Fprintf(bw, "%d%s", i+1, newline)
+		fmt.Fprintf(bw, "%s --> %s%s", formatTimecode(cue.Start, ","), formatTimecode(cue.End, ","), newline)
+		for _, line := range stackLines(&doc.Cues[i], opts.SecondaryOnTop) {
+			bw.WriteString(line)
+			bw.WriteString(newline)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// utf8BOM is the UTF-8 encoded byte order mark.
+const utf8BOM = "\uFEFF"
+
+// readLines reads all lines from r, removing a leading byte order mark
+// and normalizing CRLF and CR line endings.
+func readLines(r io.Reader) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	text := strings.TrimPrefix(string(data), utf8BOM)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	return strings.Split(text, "\n"), nil
+}
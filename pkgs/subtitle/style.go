@@ -0,0 +1,160 @@
+package subtitle
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Alignment values use the numpad layout of ASS.
+const (
+	AlignBottomLeft   = 1
+	AlignBottomCenter = 2
+	AlignBottomRight  = 3
+	AlignMiddleLeft   = 4
+	AlignMiddleCenter = 5
+	AlignMiddleRight  = 6
+	AlignTopLeft      = 7
+	AlignTopCenter    = 8
+	AlignTopRight     = 9
+)
+
+// Border styles supported by ASS.
+const (
+	BorderOutline   = 1 // outline and drop shadow
+	BorderOpaqueBox = 3 // opaque box behind the text
+)
+
+// Color is an RGBA colour as used by ASS styles.
+//
+// A follows the ASS convention: 0 is fully opaque and 255 is fully transparent.
+type Color struct {
+	R, G, B, A uint8
+}
+
+// Common colours.
+var (
+	White = Color{R: 255, G: 255, B: 255}
+	Black = Color{}
+)
+
+// String formats the colour in ASS notation (&HAABBGGRR).
+func (c Color) String() string {
+	return fmt.Sprintf("&H%02X%02X%02X%02X", c.A, c.B, c.G, c.R)
+}
+
+// WithAlpha returns the colour with the given transparency.
+func (c Color) WithAlpha(a uint8) Color {
+	c.A = a
+	return c
+}
+
+// ParseColor parses a colour in ASS notation (&HAABBGGRR or &HBBGGRR)
+// or as a decimal integer as found in SSA files.
+func ParseColor(s string) (Color, error) {
+	s = strings.TrimSpace(s)
+	var v uint64
+	var err error
+	if strings.HasPrefix(strings.ToUpper(s), "&H") {
+		v, err = strconv.ParseUint(strings.TrimSuffix(s[2:], "&"), 16, 32)
+	} else {
+		var n int64
+		n, err = strconv.ParseInt(s, 10, 64)
+		v = uint64(uint32(n))
+	}
+	if err != nil {
+		return Color{}, fmt.Errorf("invalid colour %q", s)
+	}
+
+	return Color{
+		R: uint8(v),
+		G: uint8(v >> 8),
+		B: uint8(v >> 16),
+		A: uint8(v >> 24),
+	}, nil
+}
+
+// Style describes a named ASS style.
+//
+// Sizes, outline and shadow widths and margins are in script pixels at the
+// resolution the style was designed for (see ASSOptions.StyleResY).
+type Style struct {
+	Name            string
+	FontName        string
+	FontSize        float64
+	PrimaryColour   Color
+	SecondaryColour Color
+	OutlineColour   Color
+	BackColour      Color
+	Bold            bool
+	Italic          bool
+	Underline       bool
+	StrikeOut       bool
+	ScaleX          float64
+	ScaleY          float64
+	Spacing         float64
+	Angle           float64
+	BorderStyle     int
+	Outline         float64
+	Shadow          float64
+	Alignment       int
+	MarginL         int
+	MarginR         int
+	MarginV         int
+	Encoding        int
+}
+
+// DefaultStyle returns the house style for the post-production stage
+// at 1080p: white Arial with a black outline and shadow, bottom centre.
+func DefaultStyle() Style {
+	return Style{
+		Name:            "Default",
+		FontName:        "Arial",
+		FontSize:        64,
+		PrimaryColour:   White,
+		SecondaryColour: White,
+		OutlineColour:   Black,
+		BackColour:      Black.WithAlpha(0x80),
+		ScaleX:          100,
+		ScaleY:          100,
+		BorderStyle:     BorderOutline,
+		Outline:         3,
+		Shadow:          1.5,
+		Alignment:       AlignBottomCenter,
+		MarginL:         60,
+		MarginR:         60,
+		MarginV:         50,
+		Encoding:        1,
+	}
+}
+
+// DefaultSecondaryStyle returns the style for the secondary line of
+// bilingual subtitles at 1080p: DefaultStyle() at a smaller font size.
+func DefaultSecondaryStyle() Style {
+	s := DefaultStyle()
+	s.Name = "Secondary"
+	s.FontSize = 44
+	s.Outline = 2
+	s.Shadow = 1
+	return s
+}
+
+// Scale returns a copy of the style with all resolution-dependent
+// values multiplied by factor.
+func (s Style) Scale(factor float64) Style {
+	s.FontSize = roundTo(s.FontSize*factor, 2)
+	s.Spacing = roundTo(s.Spacing*factor, 2)
+	s.Outline = roundTo(s.Outline*factor, 2)
+	s.Shadow = roundTo(s.Shadow*factor, 2)
+	s.MarginL = int(math.Round(float64(s.MarginL) * factor))
+	s.MarginR = int(math.Round(float64(s.MarginR) * factor))
+	s.MarginV = int(math.Round(float64(s.MarginV) * factor))
+	return s
+}
+
+// roundTo rounds v to the given number of decimal places.
+func roundTo(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
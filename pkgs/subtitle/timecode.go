@@ -0,0 +1,54 @@
+package subtitle
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// timecodePattern matches clock timestamps with optional hours and fraction,
+// accepting comma, period or colon as the fraction separator
+// (e.g., "01:02:03,456", "1:02:03.4", "02:03.456").
+var timecodePattern = regexp.MustCompile(`^(?:(\d+):)?(\d{1,2}):(\d{1,2})(?:[,.:](\d{1,3}))?$`)
+
+// parseTimecode parses a clock timestamp into milliseconds.
+func parseTimecode(s string) (int64, error) {
+	m := timecodePattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+
+	var hours, minutes, seconds, millis int64
+	if m[1] != "" {
+		hours, _ = strconv.ParseInt(m[1], 10, 64)
+	}
+	minutes, _ = strconv.ParseInt(m[2], 10, 64)
+	seconds, _ = strconv.ParseInt(m[3], 10, 64)
+	if m[4] != "" {
+		// The fraction is decimal: "5" means 500ms, "05" means 50ms
+		frac := m[4]
+		for len(frac) < 3 {
+			frac += "0"
+		}
+		millis, _ = strconv.ParseInt(frac, 10, 64)
+	}
+
+	if minutes > 59 || seconds > 59 {
+		return 0, fmt.Errorf("invalid timestamp %q: field out of range", s)
+	}
+
+	return ((hours*60+minutes)*60+seconds)*1000 + millis, nil
+}
+
+// formatTimecode formats milliseconds as HH:MM:SS followed by sep and
+// a three-digit millisecond fraction. Negative values are clamped to zero.
+func formatTimecode(ms int64, sep string) string {
+	if ms < 0 {
+		ms = 0
+	}
+	hours := ms / 3600000
+	minutes := ms / 60000 % 60
+	seconds := ms / 1000 % 60
+	millis := ms % 1000
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", hours, minutes, seconds, sep, millis)
+}
@@ -0,0 +1,94 @@
+package timing
+
+import (
+	"fmt"
+
+	"github.com/xifan2333/2sub/pkgs/subtitle"
+)
+
+// Profile selects the reading speed of the audience.
+type Profile string
+
+const (
+	// ProfileAdult uses the reading speed for adult programs
+	// (20 characters per second for English).
+	ProfileAdult Profile = "adult"
+
+	// ProfileChildren uses the reading speed for children's programs
+	// (17 characters per second for English).
+	ProfileChildren Profile = "children"
+)
+
+// Options contains options for adjusting cue timings.
+type Options struct {
+	// Language selects the reading speed of the language specification.
+	// If empty, the document language is used.
+	Language string
+
+	// Profile selects the adult or children's reading speed.
+	// Default: ProfileAdult
+	Profile Profile
+
+	// CPS overrides the reading speed in characters per second.
+	// Default: reading speed of the language and profile
+	// (e.g., 20 for adult English, 7 for adult Chinese)
+	CPS float64
+
+	// MinGap is the minimum gap between two cues in milliseconds.
+	// Default: 83 (two frames at 24 fps)
+	MinGap int64
+
+	// MinDuration is the minimum duration of a cue in milliseconds.
+	// Default: 833 (five sixths of a second)
+	MinDuration int64
+
+	// MaxDuration is the maximum duration of a cue in milliseconds.
+	// Default: 7000
+	MaxDuration int64
+}
+
+// Validate validates the options and sets default values.
+func (o *Options) Validate() error {
+	switch o.Profile {
+	case "":
+		o.Profile = ProfileAdult
+	case ProfileAdult, ProfileChildren:
+	default:
+		return &subtitle.ValidationError{Field: "Profile", Message: fmt.Sprintf("unknown profile %q", o.Profile)}
+	}
+
+	if o.CPS < 0 {
+		return &subtitle.ValidationError{Field: "CPS", Message: "must not be negative"}
+	}
+	if o.MinGap < 0 {
+		return &subtitle.ValidationError{Field: "MinGap", Message: "must not be negative"}
+	}
+	if o.MinDuration < 0 {
+		return &subtitle.ValidationError{Field: "MinDuration", Message: "must not be negative"}
+	}
+	if o.MaxDuration < 0 {
+		return &subtitle.ValidationError{Field: "MaxDuration", Message: "must not be negative"}
+	}
+
+	if o.CPS == 0 {
+		spec := subtitle.SpecFor(o.Language)
+		o.CPS = spec.AdultCPS
+		if o.Profile == ProfileChildren {
+			o.CPS = spec.ChildrenCPS
+		}
+	}
+	if o.MinGap == 0 {
+		o.MinGap = 83
+	}
+	if o.MinDuration == 0 {
+		o.MinDuration = 833
+	}
+	if o.MaxDuration == 0 {
+		o.MaxDuration = 7000
+	}
+	if o.MaxDuration < o.MinDuration {
+		return &subtitle.ValidationError{Field: "MaxDuration", Message: "must not be less than MinDuration"}
+	}
+
+	return nil
+}
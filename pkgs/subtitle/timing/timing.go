@@ -0,0 +1,154 @@
+// Package timing adjusts subtitle cue durations to the reading speed of the
+// audience.
+//
+// The workflow's timing rules require each cue to stay on screen for at least
+// characters ÷ reading speed seconds (20 characters per second for adult
+// English, 17 for children's programs, and per-language rates for Chinese,
+// Japanese and Korean). Adjust extends short cues into the silence that
+// follows them, enforces a minimum gap between cues and minimum and maximum
+// durations, and reports the cues that cannot be fixed without overlapping
+// the next speech.
+//
+// Cue start times are never moved, so cues stay in sync with the speech.
+//
+// Example:
+//
+//	issues, err := timing.Adjust(doc, &timing.Options{Profile: timing.ProfileChildren})
+//	if err != nil {
+//	    return err
+//	}
+//	for _, issue := range issues {
+//	    fmt.Printf("cue %d: %s\n", issue.Index, issue.Message)
+//	}
+package timing
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/xifan2333/2sub/pkgs/subtitle"
+)
+
+// Rule identifiers reported by Issue.
+const (
+	// RuleReadingSpeed means the cue is too short to be read at the
+	// configured reading speed.
+	RuleReadingSpeed = "reading-speed"
+
+	// RuleMinDuration means the cue is shorter than the minimum duration.
+	RuleMinDuration = "min-duration"
+
+	// RuleOverlap means the cue starts before the previous cue ends,
+	// or less than the minimum gap after it.
+	RuleOverlap = "overlap"
+)
+
+// Issue describes a cue whose timing could not be fixed.
+type Issue struct {
+	// Index is the index of the cue in the document.
+	Index int
+
+	// Rule identifies the timing rule that is not met.
+	Rule string
+
+	// Message provides a human-readable description of the issue.
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("cue %d: %s: %s", i.Index, i.Rule, i.Message)
+}
+
+// RequiredDuration returns the minimum display time in milliseconds needed to
+// read text at cps characters per second.
+func RequiredDuration(text string, cps float64) int64 {
+	if cps <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(float64(subtitle.CharCount(text)) * 1000 / cps))
+}
+
+// Adjust adjusts the cue timings of doc in place.
+//
+// Cues are sorted by start time first. For each cue, the end time is:
+//   - extended to the required reading time and MinDuration,
+//     as far as the next cue and MinGap allow
+//   - shortened to MaxDuration
+//   - shortened to keep MinGap before the next cue
+//
+// Reading speed is measured on the primary text of the cue.
+//
+// If opts is nil, default options for the document language are used.
+//
+// Returns the cues that still miss the reading speed, the minimum duration or
+// the minimum gap, or an error if the options are invalid.
+func Adjust(doc *subtitle.Document, opts *Options) ([]Issue, error) {
+	if doc == nil {
+		return nil, &subtitle.ValidationError{Field: "doc", Message: "is required"}
+	}
+
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Language == "" {
+		o.Language = doc.Language
+	}
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	doc.Sort()
+
+	var issues []Issue
+	for i := range doc.Cues {
+		cue := &doc.Cues[i]
+
+		// The latest end time that keeps the minimum gap to the next cue
+		limit := int64(math.MaxInt64)
+		if i+1 < len(doc.Cues) {
+			limit = doc.Cues[i+1].Start - o.MinGap
+		}
+
+		required := RequiredDuration(cue.Text(), o.CPS)
+		target := max(required, o.MinDuration)
+		target = min(target, o.MaxDuration)
+
+		end := cue.End
+		if end-cue.Start < target {
+			end = cue.Start + target
+		}
+		end = min(end, cue.Start+o.MaxDuration, limit)
+
+		if end <= cue.Start {
+			// The next cue starts within MinGap; keep the original
+			// timing rather than collapsing the cue
+			issues = append(issues, Issue{
+				Index:   i,
+				Rule:    RuleOverlap,
+				Message: fmt.Sprintf("next cue starts %d ms after this one, minimum gap is %d ms", limit+o.MinGap-cue.Start, o.MinGap),
+			})
+			continue
+		}
+		cue.End = end
+
+		duration := cue.End - cue.Start
+		switch {
+		case duration < required:
+			issues = append(issues, Issue{
+				Index: i,
+				Rule:  RuleReadingSpeed,
+				Message: fmt.Sprintf("%d characters need %d ms at %g characters per second, only %d ms available",
+					subtitle.CharCount(cue.Text()), required, o.CPS, duration),
+			})
+		case duration < o.MinDuration:
+			issues = append(issues, Issue{
+				Index:   i,
+				Rule:    RuleMinDuration,
+				Message: fmt.Sprintf("duration is %d ms, minimum is %d ms", duration, o.MinDuration),
+			})
+		}
+	}
+
+	return issues, nil
+}
@@ -0,0 +1,541 @@
+package subtitle
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TTML namespaces.
+const (
+	nsTTML      = "http://www.w3.org/ns/ttml"
+	nsStyling   = "http://www.w3.org/ns/ttml#styling"
+	nsMetadata  = "http://www.w3.org/ns/ttml#metadata"
+	nsParameter = "http://www.w3.org/ns/ttml#parameter"
+	imsc1Text   = "http://www.w3.org/ns/ttml/profile/imsc1/text"
+)
+
+var (
+	// ttmlClockPattern matches clock time with an optional fraction or frames.
+	ttmlClockPattern = regexp.MustCompile(`^(\d+):(\d{2}):(\d{2})(?:\.(\d+)|:(\d+)(?:\.\d+)?)?$`)
+
+	// ttmlOffsetPattern matches offset time such as "1.5s" or "40f".
+	ttmlOffsetPattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)(h|m|s|ms|f|t)$`)
+
+	// whitespacePattern matches runs of XML whitespace.
+	whitespacePattern = regexp.MustCompile(`[ \t\r\n]+`)
+)
+
+// WriteTTML writes a document as TTML conforming to the IMSC1 text profile.
+//
+// Cues become <p> elements in a single bottom-aligned region with clock-time
+// timing. Speakers are declared as ttm:agent elements and referenced from
+// each <p> with ttm:agent. Italic, bold, underline and strike-out markup is
+// written as styled <span> elements.
+//
+// If opts is nil, default options are used.
+func WriteTTML(w io.Writer, doc *Document, opts *TTMLOptions) error {
+	if opts == nil {
+		opts = &TTMLOptions{}
+	}
+
+	lang := opts.Language
+	if lang == "" {
+		lang = doc.Language
+	}
+	if lang == "" {
+		lang = "und"
+	}
+
+	// Declare one agent per distinct speaker, in order of appearance
+	agents := make(map[string]string)
+	var speakers []string
+	for _, cue := range doc.Cues {
+		if cue.Speaker != "" {
+			if _, ok := agents[cue.Speaker]; !ok {
+				agents[cue.Speaker] = fmt.Sprintf("speaker_%d", len(agents)+1)
+				speakers = append(speakers, cue.Speaker)
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	fmt.Fprintf(bw, `<tt xmlns="%s" xmlns:tts="%s" xmlns:ttm="%s" xmlns:ttp="%s" ttp:profile="%s" ttp:timeBase="media" xml:lang="%s">`+"\n",
+		nsTTML, nsStyling, nsMetadata, nsParameter, imsc1Text, escapeXMLText(lang))
+
+	bw.WriteString("  <head>\n")
+	if opts.Title != "" || len(speakers) > 0 {
+		bw.WriteString("    <metadata>\n")
+		if opts.Title != "" {
+			fmt.Fprintf(bw, "      <ttm:title>%s</ttm:title>\n", escapeXMLText(opts.Title))
+		}
+		for _, speaker := range speakers {
+			fmt.Fprintf(bw, `      <ttm:agent xml:id="%s" type="person"><ttm:name type="full">%s</ttm:name></ttm:agent>`+"\n",
+				agents[speaker], escapeXMLText(speaker))
+		}
+		bw.WriteString("    </metadata>\n")
+	}
+	bw.WriteString("    <styling>\n")
+	bw.WriteString(`      <style xml:id="default" tts:fontFamily="proportionalSansSerif" tts:fontSize="100%" tts:color="white" tts:textAlign="center"/>` + "\n")
+	bw.WriteString("    </styling>\n")
+	bw.WriteString("    <layout>\n")
+	bw.WriteString(`      <region xml:id="bottom" tts:origin="10% 10%" tts:extent="80% 80%" tts:displayAlign="after"/>` + "\n")
+	bw.WriteString("    </layout>\n")
+	bw.WriteString("  </head>\n")
+
+	bw.WriteString(`  <body region="bottom" style="default">` + "\n")
+	bw.WriteString("    <div>\n")
+	for i := range doc.Cues {
+		cue := &doc.Cues[i]
+		fmt.Fprintf(bw, `      <p begin="%s" end="%s"`, formatTimecode(cue.Start, "."), formatTimecode(cue.End, "."))
+		if id, ok := agents[cue.Speaker]; ok {
+			fmt.Fprintf(bw, ` ttm:agent="%s"`, id)
+		}
+		bw.WriteString(">")
+
+		state := &markupState{}
+		for j, line := range stackLines(cue, opts.SecondaryOnTop) {
+			if j > 0 {
+				bw.WriteString("<br/>")
+			}
+			for _, run := range parseMarkup(line, state) {
+				bw.WriteString(formatTTMLRun(run))
+			}
+		}
+		bw.WriteString("</p>\n")
+	}
+	bw.WriteString("    </div>\n")
+	bw.WriteString("  </body>\n")
+	bw.WriteString("</tt>\n")
+
+	return bw.Flush()
+}
+
+// formatTTMLRun formats a text run, wrapping formatted text in a styled span.
+func formatTTMLRun(run textRun) string {
+	var attrs []string
+	if run.Italic {
+		attrs = append(attrs, `tts:fontStyle="italic"`)
+	}
+	if run.Bold {
+		attrs = append(attrs, `tts:fontWeight="bold"`)
+	}
+	var decorations []string
+	if run.Underline {
+		decorations = append(decorations, "underline")
+	}
+	if run.Strike {
+		decorations = append(decorations, "lineThrough")
+	}
+	if len(decorations) > 0 {
+		attrs = append(attrs, fmt.Sprintf(`tts:textDecoration="%s"`, strings.Join(decorations, " ")))
+	}
+
+	text := escapeXMLText(run.Text)
+	if len(attrs) == 0 {
+		return text
+	}
+	return fmt.Sprintf("<span %s>%s</span>", strings.Join(attrs, " "), text)
+}
+
+// escapeXMLText escapes text for use as XML character data or in a
+// double-quoted attribute value.
+func escapeXMLText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// ttmlTiming holds the time parameters declared on the tt element.
+type ttmlTiming struct {
+	frameRate float64
+	tickRate  float64
+}
+
+// ttmlStyle is the inline formatting declared by a style element.
+type ttmlStyle struct {
+	italic, bold, underline, strike *bool
+}
+
+// ReadTTML reads a TTML document, including IMSC1 and DFXP files.
+//
+// Each <p> element with timing becomes a cue. Times may be given as clock
+// time, clock time with frames or offset time (h, m, s, ms, f, t) and are
+// resolved against the begin time of enclosing body and div elements.
+// <br/> starts a new line. Italic, bold, underline and strike-out styling
+// from inline attributes or referenced styles is mapped to markup. The
+// ttm:agent of a <p> is resolved to the agent name and stored in Cue.Speaker.
+//
+// In strict mode, paragraphs without valid timing are reported as
+// line-numbered errors. If opts is nil, lenient mode is used.
+func ReadTTML(r io.Reader, opts *ReadOptions) (*Document, error) {
+	if opts == nil {
+		opts = &ReadOptions{}
+	}
+
+	dec := xml.NewDecoder(r)
+	doc := NewDocument("")
+	timing := ttmlTiming{frameRate: 30, tickRate: 1}
+	agents := make(map[string]string)
+	styles := make(map[string]ttmlStyle)
+
+	var issues ParseErrors
+	report := func(format string, args ...interface{}) {
+		line, _ := dec.InputPos()
+		issues = append(issues, &ParseError{Format: "ttml", Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var (
+		offsets  []int64       // begin offsets of enclosing timed containers
+		cue      *Cue          // paragraph being read, nil outside of <p>
+		runs     [][]textRun   // lines of the current paragraph
+		formats  []markupState // formatting stack inside the paragraph
+		agentID  string        // agent element being read
+		inName   bool          // inside ttm:name of an agent
+		seenRoot bool
+	)
+
+	// styleElem returns the formatting of an element nested in the current
+	// one, applying referenced styles first and inline attributes last.
+	styleElem := func(attrs []xml.Attr) markupState {
+		state := markupState{}
+		if len(formats) > 0 {
+			state = formats[len(formats)-1]
+		}
+		for _, ref := range strings.Fields(attrValue(attrs, "style")) {
+			styles[ref].apply(&state)
+		}
+		parseTTMLStyle(attrs).apply(&state)
+		return state
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			line, _ := dec.InputPos()
+			return nil, &ParseError{Format: "ttml", Line: line, Message: "malformed XML", Err: err}
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "tt":
+				seenRoot = true
+				doc.Language = attrValue(t.Attr, "lang")
+				if v, err := strconv.ParseFloat(attrValue(t.Attr, "frameRate"), 64); err == nil && v > 0 {
+					timing.frameRate = v
+				}
+				// The multiplier is written as "numerator denominator"
+				if parts := strings.Fields(attrValue(t.Attr, "frameRateMultiplier")); len(parts) == 2 {
+					num, err1 := strconv.ParseFloat(parts[0], 64)
+					den, err2 := strconv.ParseFloat(parts[1], 64)
+					if err1 == nil && err2 == nil && den > 0 {
+						timing.frameRate *= num / den
+					}
+				}
+				if v, err := strconv.ParseFloat(attrValue(t.Attr, "tickRate"), 64); err == nil && v > 0 {
+					timing.tickRate = v
+				}
+
+			case "agent":
+				agentID = attrValue(t.Attr, "id")
+
+			case "name":
+				inName = agentID != ""
+
+			case "style":
+				if id := attrValue(t.Attr, "id"); id != "" && cue == nil {
+					styles[id] = parseTTMLStyle(t.Attr)
+				}
+
+			case "body", "div":
+				offset := int64(0)
+				if len(offsets) > 0 {
+					offset = offsets[len(offsets)-1]
+				}
+				if begin := attrValue(t.Attr, "begin"); begin != "" {
+					v, err := parseTTMLTime(begin, timing)
+					if err != nil {
+						report("invalid begin time %q", begin)
+					}
+					offset += v
+				}
+				offsets = append(offsets, offset)
+
+			case "p":
+				offset := int64(0)
+				if len(offsets) > 0 {
+					offset = offsets[len(offsets)-1]
+				}
+				start, end, err := parseTTMLSpan(t.Attr, timing)
+				if err != nil {
+					report("%v", err)
+					if err := dec.Skip(); err != nil {
+						line, _ := dec.InputPos()
+						return nil, &ParseError{Format: "ttml", Line: line, Message: "malformed XML", Err: err}
+					}
+					continue
+				}
+				cue = &Cue{Start: offset + start, End: offset + end}
+				if ref := attrValue(t.Attr, "agent"); ref != "" {
+					// Agent references may list several ids; use the first
+					ref = strings.Fields(ref)[0]
+					if name, ok := agents[ref]; ok {
+						cue.Speaker = name
+					} else {
+						cue.Speaker = ref
+					}
+				}
+				runs = [][]textRun{nil}
+				formats = []markupState{styleElem(t.Attr)}
+
+			case "span":
+				if cue != nil {
+					formats = append(formats, styleElem(t.Attr))
+				}
+
+			case "br":
+				if cue != nil {
+					runs = append(runs, nil)
+				}
+			}
+
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "agent":
+				agentID = ""
+			case "name":
+				inName = false
+			case "body", "div":
+				if len(offsets) > 0 {
+					offsets = offsets[:len(offsets)-1]
+				}
+			case "span":
+				if cue != nil && len(formats) > 1 {
+					formats = formats[:len(formats)-1]
+				}
+			case "p":
+				if cue != nil {
+					for _, line := range runs {
+						cue.Lines = append(cue.Lines, renderRuns(line))
+					}
+					if len(cue.Lines) == 1 && cue.Lines[0] == "" {
+						report("paragraph has no text")
+					} else {
+						doc.Add(*cue)
+					}
+					cue = nil
+				}
+			}
+
+		case xml.CharData:
+			if inName {
+				agents[agentID] += strings.TrimSpace(string(t))
+				continue
+			}
+			if cue == nil {
+				continue
+			}
+			text := whitespacePattern.ReplaceAllString(string(t), " ")
+			if text == "" {
+				continue
+			}
+			state := formats[len(formats)-1]
+			last := len(runs) - 1
+			runs[last] = append(runs[last], textRun{
+				Text:      text,
+				Italic:    state.italic,
+				Bold:      state.bold,
+				Underline: state.underline,
+				Strike:    state.strike,
+			})
+		}
+	}
+
+	if !seenRoot {
+		return nil, &ParseError{Format: "ttml", Message: "missing tt root element"}
+	}
+
+	if opts.Strict && len(issues) > 0 {
+		return nil, issues
+	}
+
+	return doc, nil
+}
+
+// renderRuns renders text runs as cue markup, trimming whitespace at the
+// line boundaries as TTML whitespace handling requires.
+func renderRuns(runs []textRun) string {
+	var b strings.Builder
+	for i, run := range runs {
+		text := run.Text
+		if i == 0 {
+			text = strings.TrimLeft(text, " ")
+		}
+		if i == len(runs)-1 {
+			text = strings.TrimRight(text, " ")
+		}
+		if text == "" {
+			continue
+		}
+
+		open, close := "", ""
+		if run.Italic {
+			open, close = open+"<i>", "</i>"+close
+		}
+		if run.Bold {
+			open, close = open+"<b>", "</b>"+close
+		}
+		if run.Underline {
+			open, close = open+"<u>", "</u>"+close
+		}
+		if run.Strike {
+			open, close = open+"<s>", "</s>"+close
+		}
+		b.WriteString(open + text + close)
+	}
+	return b.String()
+}
+
+// apply overrides the formatting in state with the properties set by the style.
+func (s ttmlStyle) apply(state *markupState) {
+	if s.italic != nil {
+		state.italic = *s.italic
+	}
+	if s.bold != nil {
+		state.bold = *s.bold
+	}
+	if s.underline != nil {
+		state.underline = *s.underline
+	}
+	if s.strike != nil {
+		state.strike = *s.strike
+	}
+}
+
+// parseTTMLStyle extracts inline formatting from tts:* attributes.
+func parseTTMLStyle(attrs []xml.Attr) ttmlStyle {
+	var s ttmlStyle
+	for _, attr := range attrs {
+		if attr.Name.Space != nsStyling && attr.Name.Space != "tts" {
+			continue
+		}
+		value := strings.TrimSpace(attr.Value)
+		switch attr.Name.Local {
+		case "fontStyle":
+			italic := value == "italic" || value == "oblique"
+			s.italic = &italic
+		case "fontWeight":
+			bold := value == "bold"
+			s.bold = &bold
+		case "textDecoration":
+			for _, v := range strings.Fields(value) {
+				switch v {
+				case "underline", "noUnderline":
+					underline := v == "underline"
+					s.underline = &underline
+				case "lineThrough", "noLineThrough":
+					strike := v == "lineThrough"
+					s.strike = &strike
+				case "none":
+					off := false
+					s.underline, s.strike = &off, &off
+				}
+			}
+		}
+	}
+	return s
+}
+
+// parseTTMLSpan parses the begin, end and dur attributes of a paragraph.
+func parseTTMLSpan(attrs []xml.Attr, timing ttmlTiming) (int64, int64, error) {
+	beginAttr := attrValue(attrs, "begin")
+	if beginAttr == "" {
+		return 0, 0, fmt.Errorf("paragraph without begin time")
+	}
+
+	begin, err := parseTTMLTime(beginAttr, timing)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if endAttr := attrValue(attrs, "end"); endAttr != "" {
+		end, err := parseTTMLTime(endAttr, timing)
+		if err != nil {
+			return 0, 0, err
+		}
+		return begin, end, nil
+	}
+
+	if durAttr := attrValue(attrs, "dur"); durAttr != "" {
+		dur, err := parseTTMLTime(durAttr, timing)
+		if err != nil {
+			return 0, 0, err
+		}
+		return begin, begin + dur, nil
+	}
+
+	return 0, 0, fmt.Errorf("paragraph without end time or duration")
+}
+
+// parseTTMLTime parses a TTML time expression into milliseconds.
+func parseTTMLTime(s string, timing ttmlTiming) (int64, error) {
+	s = strings.TrimSpace(s)
+
+	if m := ttmlClockPattern.FindStringSubmatch(s); m != nil {
+		hours, _ := strconv.ParseFloat(m[1], 64)
+		minutes, _ := strconv.ParseFloat(m[2], 64)
+		seconds, _ := strconv.ParseFloat(m[3], 64)
+		total := (hours*60+minutes)*60 + seconds
+		if m[4] != "" {
+			frac, _ := strconv.ParseFloat("0."+m[4], 64)
+			total += frac
+		}
+		if m[5] != "" {
+			frames, _ := strconv.ParseFloat(m[5], 64)
+			total += frames / timing.frameRate
+		}
+		return int64(math.Round(total * 1000)), nil
+	}
+
+	if m := ttmlOffsetPattern.FindStringSubmatch(s); m != nil {
+		v, _ := strconv.ParseFloat(m[1], 64)
+		var seconds float64
+		switch m[2] {
+		case "h":
+			seconds = v * 3600
+		case "m":
+			seconds = v * 60
+		case "s":
+			seconds = v
+		case "ms":
+			seconds = v / 1000
+		case "f":
+			seconds = v / timing.frameRate
+		case "t":
+			seconds = v / timing.tickRate
+		}
+		return int64(math.Round(seconds * 1000)), nil
+	}
+
+	return 0, fmt.Errorf("invalid time expression %q", s)
+}
+
+// attrValue returns the value of the attribute with the given local name.
+func attrValue(attrs []xml.Attr, local string) string {
+	for _, attr := range attrs {
+		if attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,291 @@
+package subtitle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Metadata keys used by the WebVTT reader and writer.
+const (
+	// MetaCueID is the cue identifier.
+	MetaCueID = "id"
+
+	// MetaVTTSettings holds the WebVTT cue settings of a cue
+	// (e.g., "align:start line:0").
+	MetaVTTSettings = "vtt:settings"
+)
+
+var (
+	// vttTimingPattern matches a WebVTT timing line with optional cue settings.
+	vttTimingPattern = regexp.MustCompile(`^(\S+)\s+-->\s+(\S+)(?:\s+(.*))?$`)
+
+	// vttStrictTimestampPattern matches a timestamp as defined by WebVTT.
+	vttStrictTimestampPattern = regexp.MustCompile(`^(?:\d{2,}:)?\d{2}:\d{2}\.\d{3}$`)
+
+	// vttVoicePattern matches an opening voice span and captures the speaker.
+	vttVoicePattern = regexp.MustCompile(`<v(?:\.[^\s>]*)?\s+([^>]*)>`)
+
+	// vttTagPattern matches any WebVTT cue text tag, including timestamps.
+	vttTagPattern = regexp.MustCompile(`</?[^>]*>`)
+)
+
+// vttEntities maps WebVTT character references to their text.
+var vttEntities = strings.NewReplacer(
+	"&lt;", "<", "&gt;", ">", "&nbsp;", "\u00a0",
+	"&lrm;", "\u200e", "&rlm;", "\u200f", "&amp;", "&",
+)
+
+// WriteVTT writes a document in WebVTT (.vtt) format.
+//
+// Cue identifiers are taken from the MetaCueID metadata key and cue settings
+// from MetaVTTSettings, falling back to opts.Settings. Cues with a speaker are
+// wrapped in a <v Speaker> voice span unless opts.DisableVoices is set.
+// Italic, bold and underline markup is preserved; other text is escaped.
+//
+// If opts is nil, default options are used.
+func WriteVTT(w io.Writer, doc *Document, opts *VTTOptions) error {
+	if opts == nil {
+		opts = &VTTOptions{}
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("WEBVTT\n")
+
+	for i := range doc.Cues {
+		cue := &doc.Cues[i]
+		bw.WriteString("\n")
+
+		if id := cue.Metadata[MetaCueID]; id != "" {
+			bw.WriteString(strings.ReplaceAll(id, "-->", "--"))
+			bw.WriteString("\n")
+		}
+
+		fmt.Fprintf(bw, "%s --> %s", formatTimecode(cue.Start, "."), formatTimecode(cue.End, "."))
+		settings := opts.Settings
+		if s, ok := cue.Metadata[MetaVTTSettings]; ok {
+			settings = s
+		}
+		if settings != "" {
+			bw.WriteString(" ")
+			bw.WriteString(settings)
+		}
+		bw.WriteString("\n")
+
+		voice := ""
+		if cue.Speaker != "" && !opts.DisableVoices {
+			voice = fmt.Sprintf("<v %s>", escapeVTT(cue.Speaker))
+		}
+
+		// A voice span extends to the end of the cue, so it opens once
+		state := &markupState{}
+		for j, line := range stackLines(cue, opts.SecondaryOnTop) {
+			if j == 0 {
+				bw.WriteString(voice)
+			}
+			for _, run := range parseMarkup(line, state) {
+				bw.WriteString(formatVTTRun(run))
+			}
+			bw.WriteString("\n")
+		}
+	}
+
+	return bw.Flush()
+}
+
+// formatVTTRun formats a text run with WebVTT tags.
+func formatVTTRun(run textRun) string {
+	text := escapeVTT(run.Text)
+	if run.Underline {
+		text = "<u>" + text + "</u>"
+	}
+	if run.Bold {
+		text = "<b>" + text + "</b>"
+	}
+	if run.Italic {
+		text = "<i>" + text + "</i>"
+	}
+	return text
+}
+
+// escapeVTT escapes characters that are not allowed in WebVTT cue text.
+func escapeVTT(s string) string {
+	s = strings.ReplaceAll(s, "&", "&amp;")
+	s = strings.ReplaceAll(s, "<", "&lt;")
+	return strings.ReplaceAll(s, ">", "&gt;")
+}
+
+// ReadVTT reads a WebVTT (.vtt) document.
+//
+// NOTE, STYLE and REGION blocks are skipped. Cue identifiers and cue settings
+// are stored in the MetaCueID and MetaVTTSettings metadata keys. The speaker
+// of the first voice span becomes Cue.Speaker. Italic, bold and underline
+// tags are kept as markup; all other tags are removed and character
+// references are decoded.
+//
+// In lenient mode a missing WEBVTT header, SRT-style comma separators and
+// missing blank lines are accepted. If opts is nil, lenient mode is used.
+func ReadVTT(r io.Reader, opts *ReadOptions) (*Document, error) {
+	if opts == nil {
+		opts = &ReadOptions{}
+	}
+
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, &ParseError{Format: "vtt", Message: "failed to read input", Err: err}
+	}
+
+	doc := NewDocument("")
+	var issues ParseErrors
+	report := func(line int, format string, args ...interface{}) {
+		issues = append(issues, &ParseError{Format: "vtt", Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	i := 0
+	if len(lines) > 0 && (lines[0] == "WEBVTT" || strings.HasPrefix(lines[0], "WEBVTT ") || strings.HasPrefix(lines[0], "WEBVTT\t")) {
+		// Skip the header block
+		for i < len(lines) && strings.TrimSpace(lines[i]) != "" {
+			i++
+		}
+	} else {
+		report(1, "missing WEBVTT header")
+	}
+
+	for i < len(lines) {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" {
+			i++
+			continue
+		}
+
+		// Skip comment, style and region blocks
+		if trimmed == "NOTE" || strings.HasPrefix(trimmed, "NOTE ") || strings.HasPrefix(trimmed, "NOTE\t") ||
+			trimmed == "STYLE" || trimmed == "REGION" {
+			for i < len(lines) && strings.TrimSpace(lines[i]) != "" {
+				i++
+			}
+			continue
+		}
+
+		// Optional cue identifier
+		id := ""
+		if !strings.Contains(trimmed, "-->") {
+			if i+1 >= len(lines) || !strings.Contains(lines[i+1], "-->") {
+				report(i+1, "unexpected text outside of a cue: %q", trimmed)
+				i++
+				continue
+			}
+			id = trimmed
+			i++
+			trimmed = strings.TrimSpace(lines[i])
+		}
+
+		lineNo := i + 1
+		m := vttTimingPattern.FindStringSubmatch(trimmed)
+		if m == nil {
+			report(lineNo, "invalid timing line %q", trimmed)
+			i = skipBlock(lines, i)
+			continue
+		}
+		if !vttStrictTimestampPattern.MatchString(m[1]) || !vttStrictTimestampPattern.MatchString(m[2]) {
+			report(lineNo, "non-standard timestamp in %q", trimmed)
+		}
+
+		start, end, err := parseVTTTiming(m[1], m[2])
+		if err != nil {
+			issues = append(issues, &ParseError{Format: "vtt", Line: lineNo, Message: "invalid timing line", Err: err})
+			i = skipBlock(lines, i)
+			continue
+		}
+		if end < start {
+			report(lineNo, "cue ends before it starts")
+		}
+
+		cue := Cue{Start: start, End: end}
+		if id != "" || m[3] != "" {
+			cue.Metadata = make(map[string]string)
+			if id != "" {
+				cue.Metadata[MetaCueID] = id
+			}
+			if m[3] != "" {
+				cue.Metadata[MetaVTTSettings] = strings.TrimSpace(m[3])
+			}
+		}
+
+		i++
+		for i < len(lines) && strings.TrimSpace(lines[i]) != "" {
+			if strings.Contains(lines[i], "-->") {
+				report(i+1, "missing blank line before cue")
+				break
+			}
+			cue.Lines = append(cue.Lines, parseVTTText(lines[i], &cue))
+			i++
+		}
+
+		if len(cue.Lines) == 0 {
+			report(lineNo, "cue has no text")
+			continue
+		}
+		doc.Add(cue)
+	}
+
+	if opts.Strict && len(issues) > 0 {
+		return nil, issues
+	}
+
+	if len(doc.Cues) == 0 && len(issues) > 0 {
+		return nil, &ParseError{Format: "vtt", Message: "no valid cues found", Err: issues}
+	}
+
+	return doc, nil
+}
+
+// parseVTTTiming parses the start and end timestamps of a timing line.
+func parseVTTTiming(start, end string) (int64, int64, error) {
+	startMS, err := parseTimecode(start)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	endMS, err := parseTimecode(end)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return startMS, endMS, nil
+}
+
+// parseVTTText converts a line of WebVTT cue text to cue markup,
+// recording the first voice span as the cue speaker.
+func parseVTTText(line string, cue *Cue) string {
+	if m := vttVoicePattern.FindStringSubmatch(line); m != nil && cue.Speaker == "" {
+		cue.Speaker = vttEntities.Replace(strings.TrimSpace(m[1]))
+	}
+
+	line = vttTagPattern.ReplaceAllStringFunc(line, func(tag string) string {
+		name := strings.ToLower(strings.Trim(tag, "</>"))
+		if i := strings.IndexAny(name, ". \t"); i >= 0 {
+			name = name[:i]
+		}
+		switch name {
+		case "i", "b", "u":
+			if strings.HasPrefix(tag, "</") {
+				return "</" + name + ">"
+			}
+			return "<" + name + ">"
+		}
+		return ""
+	})
+
+	return vttEntities.Replace(strings.TrimRight(line, " \t"))
+}
+
+// skipBlock returns the index of the first blank line at or after i.
+func skipBlock(lines []string, i int) int {
+	for i < len(lines) && strings.TrimSpace(lines[i]) != "" {
+		i++
+	}
+	return i
+}